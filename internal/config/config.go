@@ -39,15 +39,60 @@ func NewConfig(env string) (*Config, error) {
 
 	return &Config{
 		Key: map[string]string{
-			"POSTGRES_DB_NAME":  getEnv(env+"_POSTGRES_DB_NAME", ""),
-			"POSTGRES_USER":     getEnv(env+"_POSTGRES_USER", ""),
-			"POSTGRES_PASSWORD": getEnv(env+"_POSTGRES_PASSWORD", ""),
-			"POSTGRES_HOST":     getEnv(env+"_POSTGRES_HOST", ""),
-			"POSTGRES_PORT":     getEnv(env+"_POSTGRES_PORT", ""),
-			"REDIS_HOST":        getEnv(env+"_REDIS_HOST", ""),
-			"REDIS_PORT":        getEnv(env+"_REDIS_PORT", ""),
-			"JWT_SECRET":        getEnv(env+"_JWT_SECRET", ""),
-			"PORT":              getEnv("PORT", "8080"),
+			"POSTGRES_DB_NAME":          getEnv(env+"_POSTGRES_DB_NAME", ""),
+			"POSTGRES_USER":             getEnv(env+"_POSTGRES_USER", ""),
+			"POSTGRES_PASSWORD":         getEnv(env+"_POSTGRES_PASSWORD", ""),
+			"POSTGRES_HOST":             getEnv(env+"_POSTGRES_HOST", ""),
+			"POSTGRES_PORT":             getEnv(env+"_POSTGRES_PORT", ""),
+			"REDIS_HOST":                getEnv(env+"_REDIS_HOST", ""),
+			"REDIS_PORT":                getEnv(env+"_REDIS_PORT", ""),
+			"REDIS_STREAM_POOL_SIZE":    getEnv(env+"_REDIS_STREAM_POOL_SIZE", "1000"),
+			"JWT_SECRET":                getEnv(env+"_JWT_SECRET", ""),
+			"JWT_ALGORITHM":             getEnv(env+"_JWT_ALGORITHM", ""),
+			"JWT_KEYS":                  getEnv(env+"_JWT_KEYS", ""),
+			"JWT_ACTIVE_KEY_ID":         getEnv(env+"_JWT_ACTIVE_KEY_ID", ""),
+			"APP_URL":                   getEnv(env+"_APP_URL", ""),
+			"EXPORT_SIGNING_KEY":        getEnv(env+"_EXPORT_SIGNING_KEY", ""),
+			"MAIL_PROVIDER":             getEnv(env+"_MAIL_PROVIDER", ""),
+			"MAIL_FROM":                 getEnv(env+"_MAIL_FROM", ""),
+			"SMTP_HOST":                 getEnv(env+"_SMTP_HOST", ""),
+			"SMTP_PORT":                 getEnv(env+"_SMTP_PORT", ""),
+			"SMTP_USERNAME":             getEnv(env+"_SMTP_USERNAME", ""),
+			"SMTP_PASSWORD":             getEnv(env+"_SMTP_PASSWORD", ""),
+			"SMS_PROVIDER":              getEnv(env+"_SMS_PROVIDER", ""),
+			"SMS_FROM":                  getEnv(env+"_SMS_FROM", ""),
+			"TWILIO_ACCOUNT_SID":        getEnv(env+"_TWILIO_ACCOUNT_SID", ""),
+			"TWILIO_AUTH_TOKEN":         getEnv(env+"_TWILIO_AUTH_TOKEN", ""),
+			"STORAGE_PROVIDER":          getEnv(env+"_STORAGE_PROVIDER", "filesystem"),
+			"STORAGE_DIR":               getEnv(env+"_STORAGE_DIR", "./uploads"),
+			"STORAGE_PUBLIC_URL":        getEnv(env+"_STORAGE_PUBLIC_URL", ""),
+			"S3_ENDPOINT":               getEnv(env+"_S3_ENDPOINT", ""),
+			"S3_REGION":                 getEnv(env+"_S3_REGION", ""),
+			"S3_BUCKET":                 getEnv(env+"_S3_BUCKET", ""),
+			"S3_ACCESS_KEY_ID":          getEnv(env+"_S3_ACCESS_KEY_ID", ""),
+			"S3_SECRET_ACCESS_KEY":      getEnv(env+"_S3_SECRET_ACCESS_KEY", ""),
+			"PHOTO_MODERATOR":           getEnv(env+"_PHOTO_MODERATOR", "manual"),
+			"PUSH_PROVIDER":             getEnv(env+"_PUSH_PROVIDER", ""),
+			"FCM_CREDENTIALS_FILE":      getEnv(env+"_FCM_CREDENTIALS_FILE", ""),
+			"APNS_KEY_FILE":             getEnv(env+"_APNS_KEY_FILE", ""),
+			"APNS_KEY_ID":               getEnv(env+"_APNS_KEY_ID", ""),
+			"APNS_TEAM_ID":              getEnv(env+"_APNS_TEAM_ID", ""),
+			"APNS_TOPIC":                getEnv(env+"_APNS_TOPIC", ""),
+			"APNS_PRODUCTION":           getEnv(env+"_APNS_PRODUCTION", "false"),
+			"OIDC_CALLBACK_URL":         getEnv(env+"_OIDC_CALLBACK_URL", ""),
+			"OIDC_GOOGLE_CLIENT_ID":     getEnv(env+"_OIDC_GOOGLE_CLIENT_ID", ""),
+			"OIDC_GOOGLE_CLIENT_SECRET": getEnv(env+"_OIDC_GOOGLE_CLIENT_SECRET", ""),
+			"OIDC_APPLE_CLIENT_ID":      getEnv(env+"_OIDC_APPLE_CLIENT_ID", ""),
+			"OIDC_APPLE_CLIENT_SECRET":  getEnv(env+"_OIDC_APPLE_CLIENT_SECRET", ""),
+			"OIDC_FAKE_ENABLED":         getEnv(env+"_OIDC_FAKE_ENABLED", "false"),
+			"TRUSTED_PROXIES":           getEnv(env+"_TRUSTED_PROXIES", ""),
+			"RATE_LIMIT_ENABLED":        getEnv(env+"_RATE_LIMIT_ENABLED", "true"),
+			"RATE_LIMIT_DEFAULT":        getEnv(env+"_RATE_LIMIT_DEFAULT", "300/1m"),
+			"RATE_LIMIT_SIGN_UP":        getEnv(env+"_RATE_LIMIT_SIGN_UP", "5/1h"),
+			"RATE_LIMIT_SIGN_IN":        getEnv(env+"_RATE_LIMIT_SIGN_IN", "10/1m"),
+			"RATE_LIMIT_SWIPE":          getEnv(env+"_RATE_LIMIT_SWIPE", "60/1m"),
+			"RATE_LIMIT_PROFILE":        getEnv(env+"_RATE_LIMIT_PROFILE", "30/1m"),
+			"PORT":                      getEnv("PORT", "8080"),
 		},
 		Env: env,
 	}, nil
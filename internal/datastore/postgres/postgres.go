@@ -14,6 +14,9 @@ func InitializeDB(username, password, dbName, host, port string) (*gorm.DB, erro
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, username, password, dbName, port)
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+
+		// Unique violations come back as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
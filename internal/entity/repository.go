@@ -1,17 +1,285 @@
 package entity
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // TODO Refactor premium to use payment transaction table
 type User struct {
 	ID        uint      `gorm:"primaryKey;column:id"`
 	Name      string    `gorm:"not null;column:name"`
-	Email     string    `gorm:"unique;not null;column:email"`
-	Username  string    `gorm:"unique;column:username"`
-	Password  string    `gorm:"not null;column:password"`
+	Email     string    `gorm:"column:email;->"` // Stored in AuthCredential
+	Username  string    `gorm:"column:username;->"`
 	IsPremium bool      `gorm:"not null;column:is_premium"`
 	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null"`
 	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null"`
+
+	Status         AccountStatus `gorm:"column:status;type:smallint;not null;default:1"`
+	SuspendedUntil *time.Time    `gorm:"column:suspended_until;type:timestamp"`
+
+	Role Role `gorm:"column:role;type:varchar(16);not null;default:user"`
+
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at;type:timestamp"`
+
+	// E.164, stored in AuthCredential with the email and the username
+	Phone           string     `gorm:"column:phone;->"`
+	PhoneVerifiedAt *time.Time `gorm:"column:phone_verified_at;type:timestamp"`
+
+	// Set while a deleted account waits to be erased, signing in before then
+	// cancels the deletion
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;type:timestamp"`
+	ErasedAt            *time.Time `gorm:"column:erased_at;type:timestamp"`
+
+	// Failed erasures, the eraser leaves the user until ErasureRetryAt
+	ErasureAttempts int        `gorm:"column:erasure_attempts;type:smallint;not null;default:0" json:"-"`
+	ErasureRetryAt  *time.Time `gorm:"column:erasure_retry_at;type:timestamp" json:"-"`
+
+	// Only loaded for dating profiles, approved ones ordered by position
+	Photos []Photo `gorm:"foreignKey:UserID"`
+}
+
+// A suspension lifts itself once SuspendedUntil has passed
+func (u *User) AccountStatusAt(now time.Time) AccountStatus {
+	if u.Status == AccountSuspended && u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil) {
+		return AccountActive
+	}
+
+	return u.Status
+}
+
+func (u *User) IsActiveAt(now time.Time) bool {
+	return u.AccountStatusAt(now) == AccountActive
+}
+
+// Deleted but not erased yet, the deletion can still be cancelled
+func (u *User) IsPendingDeletionAt(now time.Time) bool {
+	return u.Status == AccountDeleted && u.DeletionScheduledAt != nil && now.Before(*u.DeletionScheduledAt)
+}
+
+type AccountStatus uint
+
+const (
+	AccountActive    AccountStatus = iota + 1
+	AccountSuspended               //Until SuspendedUntil
+	AccountBanned
+	AccountDeleted
+)
+
+func (s AccountStatus) String() string {
+	switch s {
+	case AccountActive:
+		return "active"
+	case AccountSuspended:
+		return "suspended"
+	case AccountBanned:
+		return "banned"
+	case AccountDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+type Role string
+
+// Ordered from least to most privileged
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleUser:
+		return 1
+	case RoleModerator:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func (r Role) IsValid() bool {
+	return r.rank() > 0
+}
+
+// Whether the role has at least the privileges of the required role
+func (r Role) HasAtLeast(required Role) bool {
+	return r.IsValid() && r.rank() >= required.rank()
+}
+
+// Whether the role is strictly more privileged than the other one
+func (r Role) Outranks(other Role) bool {
+	return r.IsValid() && r.rank() > other.rank()
+}
+
+type PasswordAlgorithm string
+
+const (
+	// bcrypt of the password followed by the email, left from before the
+	// algorithm was stored, rehashed on the next sign-in
+	PasswordBcryptEmail PasswordAlgorithm = "bcrypt_email"
+	PasswordBcrypt      PasswordAlgorithm = "bcrypt"
+)
+
+// A password hash with the parameters it was made with
+type PasswordHash struct {
+	Hash      string
+	Algorithm PasswordAlgorithm
+	Cost      int
+}
+
+// The password of a user, users signing in only with a provider or a phone
+// don't have one. The hash is never encoded to JSON, so it can't end up in a
+// cache or a response
+type AuthCredential struct {
+	UserID uint `gorm:"primaryKey;column:user_id"`
+
+	// What the user signs in with, each unique. Users have an email, a phone
+	// or both. Empty values are stored as NULL
+	Email    string `gorm:"column:email;default:null"`
+	Username string `gorm:"column:username;default:null"`
+	Phone    string `gorm:"column:phone;type:varchar(16);default:null"` // E.164
+
+	// Empty for users without a password
+	PasswordHash      string            `gorm:"column:password_hash;default:null" json:"-"`
+	PasswordAlgorithm PasswordAlgorithm `gorm:"column:password_algorithm;type:varchar(16);default:null"`
+	PasswordCost      int               `gorm:"column:password_cost;type:smallint;default:null"`
+	PasswordSalt      string            `gorm:"column:password_salt;default:null" json:"-"` //The email a legacy hash was salted with
+	PasswordChangedAt *time.Time        `gorm:"column:password_changed_at;type:timestamp"`
+	LastUsedAt        *time.Time        `gorm:"column:last_used_at;type:timestamp"`
+	CreatedAt         time.Time         `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt         time.Time         `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+type AccountStatusAudit struct {
+	ID             uint          `gorm:"primaryKey;column:id"`
+	UserID         uint          `gorm:"column:user_id;not null"`
+	FromStatus     AccountStatus `gorm:"column:from_status;type:smallint;not null"`
+	ToStatus       AccountStatus `gorm:"column:to_status;type:smallint;not null"`
+	SuspendedUntil *time.Time    `gorm:"column:suspended_until;type:timestamp"`
+	Reason         string        `gorm:"column:reason;not null"`
+	ActorID        *uint         `gorm:"column:actor_id"`
+	CreatedAt      time.Time     `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+type DataExportStatus uint
+
+const (
+	DataExportPending DataExportStatus = iota + 1 //Waiting for the export worker
+	DataExportReady                               //Archive built, downloadable until ExpiresAt
+	DataExportFailed                              //Gave up after too many attempts
+	DataExportExpired                             //Archive dropped after ExpiresAt
+)
+
+func (s DataExportStatus) String() string {
+	switch s {
+	case DataExportPending:
+		return "pending"
+	case DataExportReady:
+		return "ready"
+	case DataExportFailed:
+		return "failed"
+	case DataExportExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// Archive is a ZIP of everything held about the user, built by the export
+// worker and dropped once ExpiresAt has passed
+type DataExport struct {
+	ID           uint             `gorm:"primaryKey;column:id"`
+	UserID       uint             `gorm:"column:user_id;not null"`
+	Status       DataExportStatus `gorm:"column:status;type:smallint;not null"`
+	Archive      []byte           `gorm:"column:archive;type:bytea"`
+	Attempts     int              `gorm:"column:attempts;not null"`
+	LastError    string           `gorm:"column:last_error"`
+	CompletedAt  *time.Time       `gorm:"column:completed_at;type:timestamp"`
+	ExpiresAt    *time.Time       `gorm:"column:expires_at;type:timestamp"`
+	ClaimedUntil *time.Time       `gorm:"column:claimed_until;type:timestamp"` //Lease of the worker building it
+	CreatedAt    time.Time        `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt    time.Time        `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+// PersonalData is everything held about a user that goes into a data export.
+// Swipes and matches only cover the ones the user made, who swiped on the
+// user is other users' data
+type PersonalData struct {
+	User                   User
+	Identities             []Identity
+	Photos                 []Photo
+	Swipes                 []SwipeTransaction
+	Matches                []Match
+	Blocks                 []Block
+	Reports                []Report
+	Devices                []Device
+	NotificationPreference *NotificationPreference
+	AccountStatusAudits    []AccountStatusAudit
+	SecurityAuditLogs      []SecurityAuditLog
+	PasswordChangedAt      *time.Time
+	TwoFactorEnabledAt     *time.Time
+}
+
+// Users who liked or super-liked each other, matched at the later swipe. The
+// match flag on swipes isn't set for super-likes, so matches are read from the
+// swipe pairs
+type Match struct {
+	UserID uint      `gorm:"column:user_id"`
+	Time   time.Time `gorm:"column:time"`
+}
+
+// A profile photo, its variants are stored under StorageKey in the blob store.
+// URLs is filled from the blob store when the photo is returned
+type Photo struct {
+	ID         uint        `gorm:"primaryKey;column:id"`
+	UserID     uint        `gorm:"column:user_id;not null"`
+	StorageKey string      `gorm:"column:storage_key;unique;not null"`
+	Position   int         `gorm:"column:position;type:smallint;not null"`
+	IsPrimary  bool        `gorm:"column:is_primary;not null"`
+	Width      int         `gorm:"column:width;not null"`
+	Height     int         `gorm:"column:height;not null"`
+	Status     PhotoStatus `gorm:"column:status;type:smallint;not null"`
+
+	// Reason given by the moderator or the reviewer, shown to the owner
+	ModerationNote     string `gorm:"column:moderation_note;not null"`
+	ModerationAttempts int    `gorm:"column:moderation_attempts;not null"`
+
+	// Set when the moderator left the photo to a reviewer
+	FlaggedAt  *time.Time `gorm:"column:flagged_at;type:timestamp"`
+	ReviewedAt *time.Time `gorm:"column:reviewed_at;type:timestamp"`
+
+	// Lease of the moderation worker handling it
+	ClaimedUntil *time.Time        `gorm:"column:claimed_until;type:timestamp"`
+	CreatedAt    time.Time         `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt    time.Time         `gorm:"column:updated_at;type:timestamp;not null"`
+	URLs         map[string]string `gorm:"-"`
+}
+
+type PhotoStatus uint
+
+const (
+	PhotoPending  PhotoStatus = iota + 1 //Waiting for the moderator, or a reviewer once flagged
+	PhotoApproved                        //Shown to other users
+	PhotoRejected                        //Only shown to the owner
+)
+
+func (s PhotoStatus) String() string {
+	switch s {
+	case PhotoPending:
+		return "pending"
+	case PhotoApproved:
+		return "approved"
+	case PhotoRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
 }
 
 type SwipeTransaction struct {
@@ -27,6 +295,189 @@ type SwipeTransaction struct {
 	IsMatched bool `gorm:"column:is_matched;not null"`
 }
 
+type Block struct {
+	ID        uint      `gorm:"primaryKey;column:id"`
+	UserID    uint      `gorm:"column:user_id;not null"`
+	BlockedID uint      `gorm:"column:blocked_id;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+// Opaque refresh tokens are stored as a SHA-256 hash, every rotation creates
+// a new token in the same family so reusing an old one can revoke them all
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey;column:id"`
+	UserID    uint       `gorm:"column:user_id;not null"`
+	FamilyID  string     `gorm:"column:family_id;not null"`
+	TokenHash string     `gorm:"column:token_hash;unique;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamp;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamp"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:timestamp"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+type TokenPurpose string
+
+const (
+	TokenEmailVerification TokenPurpose = "email_verification"
+	TokenPasswordReset     TokenPurpose = "password_reset"
+)
+
+// Single-use token sent by email, stored as a SHA-256 hash. Email is the
+// address the token was sent to, the token is only valid while it matches
+type OneTimeToken struct {
+	ID        uint         `gorm:"primaryKey;column:id"`
+	UserID    uint         `gorm:"column:user_id;not null"`
+	Purpose   TokenPurpose `gorm:"column:purpose;not null"`
+	TokenHash string       `gorm:"column:token_hash;unique;not null"`
+	Email     string       `gorm:"column:email;not null"`
+	ExpiresAt time.Time    `gorm:"column:expires_at;type:timestamp;not null"`
+	UsedAt    *time.Time   `gorm:"column:used_at;type:timestamp"`
+	CreatedAt time.Time    `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+// A user's account at an OpenID provider, Subject is the provider's ID of
+// the user. Email is the one the provider gave when the identity was linked
+type Identity struct {
+	ID        uint      `gorm:"primaryKey;column:id"`
+	UserID    uint      `gorm:"column:user_id;not null"`
+	Provider  string    `gorm:"column:provider;type:varchar(32);not null"`
+	Subject   string    `gorm:"column:subject;not null"`
+	Email     string    `gorm:"column:email"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+// Kept between the start of an OpenID sign-in and its callback
+type OIDCState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// TOTP secret of a user, pending until a first code confirms the enrollment.
+// LastUsedStep is the time step of the last accepted code, a code can't be
+// used twice
+type TOTPCredential struct {
+	UserID       uint       `gorm:"primaryKey;column:user_id"`
+	Secret       string     `gorm:"column:secret;not null" json:"-"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at;type:timestamp"`
+	LastUsedStep int64      `gorm:"column:last_used_step;not null"`
+	CreatedAt    time.Time  `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+// Single-use code to sign in without the authenticator, stored as a SHA-256 hash
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;column:id"`
+	UserID    uint       `gorm:"column:user_id;not null"`
+	CodeHash  string     `gorm:"column:code_hash;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamp"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+type SecurityEvent string
+
+const (
+	SecurityEventSignInFailed SecurityEvent = "sign_in_failed"
+	SecurityEventSignInLocked SecurityEvent = "sign_in_locked"
+)
+
+// Identifier is the email or username the request used, UserID is nil when
+// it didn't match any user
+type SecurityAuditLog struct {
+	ID         uint          `gorm:"primaryKey;column:id"`
+	UserID     *uint         `gorm:"column:user_id"`
+	Event      SecurityEvent `gorm:"column:event;type:varchar(32);not null"`
+	Identifier string        `gorm:"column:identifier;not null"`
+	IP         string        `gorm:"column:ip;not null"`
+	CreatedAt  time.Time     `gorm:"column:created_at;type:timestamp;not null"`
+}
+
+type Report struct {
+	ID             uint              `gorm:"primaryKey;column:id"`
+	ReporterID     uint              `gorm:"column:reporter_id;not null"`
+	ReportedID     uint              `gorm:"column:reported_id;not null"`
+	TargetType     ReportTargetType  `gorm:"column:target_type;not null"`
+	TargetID       *uint             `gorm:"column:target_id"`
+	Reason         ReportReason      `gorm:"column:reason;not null"`
+	Details        string            `gorm:"column:details;not null"`
+	Status         ReportStatus      `gorm:"column:status;type:smallint;not null"`
+	Action         *ModerationAction `gorm:"column:action"`
+	ResolutionNote string            `gorm:"column:resolution_note;not null"`
+	ReviewerID     *uint             `gorm:"column:reviewer_id"`
+	ReviewedAt     *time.Time        `gorm:"column:reviewed_at;type:timestamp"`
+	CreatedAt      time.Time         `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt      time.Time         `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+type ReportTargetType string
+
+const (
+	ReportTargetProfile ReportTargetType = "profile"
+	ReportTargetMessage ReportTargetType = "message"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam          ReportReason = "spam"
+	ReportReasonInappropriate ReportReason = "inappropriate"
+	ReportReasonHarassment    ReportReason = "harassment"
+	ReportReasonFakeProfile   ReportReason = "fake_profile"
+	ReportReasonUnderage      ReportReason = "underage"
+	ReportReasonOther         ReportReason = "other"
+)
+
+func (r ReportReason) IsValid() bool {
+	switch r {
+	case ReportReasonSpam, ReportReasonInappropriate, ReportReasonHarassment, ReportReasonFakeProfile, ReportReasonUnderage, ReportReasonOther:
+		return true
+	default:
+		return false
+	}
+}
+
+type ReportStatus uint
+
+const (
+	ReportStatusPending   ReportStatus = iota + 1 //Waiting in the moderation queue
+	ReportStatusDismissed                         //Reviewed, no action needed
+	ReportStatusActioned                          //Reviewed, a moderation action was taken
+)
+
+func (s ReportStatus) String() string {
+	switch s {
+	case ReportStatusPending:
+		return "pending"
+	case ReportStatusDismissed:
+		return "dismissed"
+	case ReportStatusActioned:
+		return "actioned"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseReportStatus(s string) (ReportStatus, bool) {
+	for _, status := range []ReportStatus{ReportStatusPending, ReportStatusDismissed, ReportStatusActioned} {
+		if status.String() == s {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+type ModerationAction string
+
+const (
+	ModerationWarn    ModerationAction = "warn"
+	ModerationSuspend ModerationAction = "suspend"
+	ModerationBan     ModerationAction = "ban"
+)
+
+func (a ModerationAction) IsValid() bool {
+	return a == ModerationWarn || a == ModerationSuspend || a == ModerationBan
+}
+
 type Action uint
 
 const (
@@ -74,3 +525,143 @@ func (o Outcome) String() string {
 		return "Unknown"
 	}
 }
+
+type EventType string
+
+const (
+	EventMatch  EventType = "match"  //When both user like each other
+	EventMissed EventType = "missed" //When user pass the other user which likes the user
+)
+
+// UserEvent is a realtime event delivered to a single user, the ID is the
+// Redis stream entry ID which is also used as the SSE event id for resuming
+type UserEvent struct {
+	ID   string          `json:"id"`
+	Type EventType       `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type MatchEventData struct {
+	ProfileID int `json:"profile_id"`
+}
+
+type Platform string
+
+const (
+	PlatformFCM  Platform = "fcm"
+	PlatformAPNs Platform = "apns"
+)
+
+type Device struct {
+	ID        uint      `gorm:"primaryKey;column:id"`
+	UserID    uint      `gorm:"column:user_id;not null"`
+	Platform  Platform  `gorm:"column:platform;not null"`
+	Token     string    `gorm:"unique;column:token;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+type NotificationType string
+
+const (
+	NotificationMatch     NotificationType = "match"      //When the user liked earlier and got liked back
+	NotificationSuperLike NotificationType = "super_like" //When someone super likes the user
+)
+
+// Missing row means every notification type is enabled
+type NotificationPreference struct {
+	UserID    uint      `gorm:"primaryKey;column:user_id"`
+	Match     bool      `gorm:"column:match;not null"`
+	SuperLike bool      `gorm:"column:super_like;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null"`
+}
+
+func DefaultNotificationPreference(userID uint) NotificationPreference {
+	return NotificationPreference{
+		UserID:    userID,
+		Match:     true,
+		SuperLike: true,
+	}
+}
+
+func (p NotificationPreference) IsEnabled(t NotificationType) bool {
+	switch t {
+	case NotificationMatch:
+		return p.Match
+	case NotificationSuperLike:
+		return p.SuperLike
+	default:
+		return false
+	}
+}
+
+// PushJob is a single notification to a single device waiting in the dispatch queue
+type PushJob struct {
+	ID       string            `json:"id"`
+	DeviceID uint              `json:"device_id"`
+	Platform Platform          `json:"platform"`
+	Token    string            `json:"token"`
+	Type     NotificationType  `json:"type"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data"`
+	Attempts int               `json:"attempts"`
+
+	// Queue entry the job was dequeued from, used to acknowledge it
+	Receipt string `json:"-"`
+}
+
+// Domain event topics, written to the outbox and relayed to the event bus
+const (
+	TopicSwipeCreated = "swipe.created"
+	TopicMatchCreated = "match.created"
+)
+
+// OutboxEvent is written in the same transaction as the change it describes,
+// the relay worker publishes it afterwards so it survives a crash
+type OutboxEvent struct {
+	ID          uint       `gorm:"primaryKey;column:id"`
+	Topic       string     `gorm:"column:topic;not null"`
+	Payload     string     `gorm:"column:payload;type:jsonb;not null"`
+	Attempts    int        `gorm:"column:attempts;not null"`
+	LastError   string     `gorm:"column:last_error"`
+	CreatedAt   time.Time  `gorm:"column:created_at;type:timestamp;not null"`
+	PublishedAt *time.Time `gorm:"column:published_at;type:timestamp"`
+
+	// Not handed out before this, set while the event is claimed or waiting for a retry
+	AvailableAt *time.Time `gorm:"column:available_at;type:timestamp"`
+
+	// Set once the event ran out of attempts, it's kept for inspection
+	DeadAt *time.Time `gorm:"column:dead_at;type:timestamp"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+func NewOutboxEvent(topic string, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		Topic:   topic,
+		Payload: string(data),
+	}, nil
+}
+
+type SwipeCreatedEvent struct {
+	SwipeID uint      `json:"swipe_id"`
+	UserID  int       `json:"user_id"`
+	ToID    int       `json:"to_id"`
+	Action  Action    `json:"action"`
+	Outcome Outcome   `json:"outcome"`
+	Time    time.Time `json:"time"`
+}
+
+type MatchCreatedEvent struct {
+	UserID        int       `json:"user_id"`
+	MatchedUserID int       `json:"matched_user_id"`
+	Time          time.Time `json:"time"`
+}
@@ -3,8 +3,12 @@ package entity
 import (
 	"context"
 	"regexp"
+	"strings"
 )
 
+// E.164, a + and the country code followed by up to 15 digits in total
+var phoneRegex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
 type CreateUserRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
@@ -75,3 +79,345 @@ type MatchLikeRequest struct {
 type MatchGetProfileRequest struct {
 	ExcludeProfiles []int `json:"exclude_profiles"`
 }
+
+type RegisterDeviceRequest struct {
+	Token    string   `json:"token"`
+	Platform Platform `json:"platform"`
+}
+
+func (r *RegisterDeviceRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Token == "" {
+		problems["Token"] = append(problems["Token"], "Token is required")
+	}
+
+	if len(r.Token) > 512 {
+		problems["Token"] = append(problems["Token"], "Token is too long")
+	}
+
+	if r.Platform != PlatformFCM && r.Platform != PlatformAPNs {
+		problems["Platform"] = append(problems["Platform"], "Platform should be either fcm or apns")
+	}
+
+	return problems
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Match     *bool `json:"match"`
+	SuperLike *bool `json:"super_like"`
+}
+
+type CreateReportRequest struct {
+	ReportedUserID int              `json:"reported_user_id"`
+	TargetType     ReportTargetType `json:"target_type"`
+	Reason         ReportReason     `json:"reason"`
+	Details        string           `json:"details"`
+}
+
+func (r *CreateReportRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.ReportedUserID <= 0 {
+		problems["ReportedUserID"] = append(problems["ReportedUserID"], "Reported user is required")
+	}
+
+	if r.TargetType == "" {
+		r.TargetType = ReportTargetProfile
+	}
+
+	// There is no messaging yet, so no message a report could point to
+	if r.TargetType != ReportTargetProfile {
+		problems["TargetType"] = append(problems["TargetType"], "Only profiles can be reported")
+	}
+
+	if !r.Reason.IsValid() {
+		problems["Reason"] = append(problems["Reason"], "Invalid reason")
+	}
+
+	if len(r.Details) > 2000 {
+		problems["Details"] = append(problems["Details"], "Details should not exceed 2000 characters")
+	}
+
+	return problems
+}
+
+type ResolveReportRequest struct {
+	Action ModerationAction `json:"action"`
+	Note   string           `json:"note"`
+
+	// Only used by the suspend action
+	SuspendDays int `json:"suspend_days"`
+}
+
+func (r *ResolveReportRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if !r.Action.IsValid() {
+		problems["Action"] = append(problems["Action"], "Action should be one of warn, suspend or ban")
+	}
+
+	if r.Action == ModerationSuspend && r.SuspendDays <= 0 {
+		problems["SuspendDays"] = append(problems["SuspendDays"], "Suspend days is required when suspending")
+	}
+
+	return problems
+}
+
+type DismissReportRequest struct {
+	Note string `json:"note"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r *RefreshTokenRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.RefreshToken == "" {
+		problems["RefreshToken"] = append(problems["RefreshToken"], "Refresh token is required")
+	}
+
+	return problems
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (r *VerifyEmailRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Token == "" {
+		problems["Token"] = append(problems["Token"], "Token is required")
+	}
+
+	return problems
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (r *ForgotPasswordRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Email == "" {
+		problems["Email"] = append(problems["Email"], "Email is required")
+	}
+
+	return problems
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r *ResetPasswordRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Token == "" {
+		problems["Token"] = append(problems["Token"], "Token is required")
+	}
+
+	if r.Password == "" {
+		problems["Password"] = append(problems["Password"], "Password is required")
+	}
+
+	if len([]byte(r.Password)) > 72 {
+		problems["Password"] = append(problems["Password"], "Password length should not exceed 72 bytes")
+	}
+
+	return problems
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (r *ChangePasswordRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.CurrentPassword == "" {
+		problems["CurrentPassword"] = append(problems["CurrentPassword"], "Current password is required")
+	}
+
+	if r.NewPassword == "" {
+		problems["NewPassword"] = append(problems["NewPassword"], "New password is required")
+	}
+
+	if len([]byte(r.NewPassword)) > 72 {
+		problems["NewPassword"] = append(problems["NewPassword"], "Password length should not exceed 72 bytes")
+	}
+
+	return problems
+}
+
+// The password is asked again so a stolen token can't take over the account
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (r *ChangeEmailRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Email == "" {
+		problems["Email"] = append(problems["Email"], "Email is required")
+	}
+
+	if len(r.Email) > 255 {
+		problems["Email"] = append(problems["Email"], "Email is too long")
+	}
+
+	if r.Email != "" {
+		emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+		if !regexp.MustCompile(emailRegex).MatchString(strings.TrimSpace(r.Email)) {
+			problems["Email"] = append(problems["Email"], "Invalid email format")
+		}
+	}
+
+	if r.Password == "" {
+		problems["Password"] = append(problems["Password"], "Password is required")
+	}
+
+	return problems
+}
+
+// Code is a TOTP code or, except when enrolling, a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r *MFACodeRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Code == "" {
+		problems["Code"] = append(problems["Code"], "Code is required")
+	}
+
+	return problems
+}
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+func (r *MFAChallengeRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.ChallengeToken == "" {
+		problems["ChallengeToken"] = append(problems["ChallengeToken"], "Challenge token is required")
+	}
+
+	if r.Code == "" {
+		problems["Code"] = append(problems["Code"], "Code is required")
+	}
+
+	return problems
+}
+
+type PhoneOTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+func (r *PhoneOTPRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if !phoneRegex.MatchString(r.Phone) {
+		problems["Phone"] = append(problems["Phone"], "Phone should be in E.164 format, e.g. +6281234567890")
+	}
+
+	return problems
+}
+
+// The name is only used when the phone signs up
+type PhoneSignInRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+}
+
+func (r *PhoneSignInRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if !phoneRegex.MatchString(r.Phone) {
+		problems["Phone"] = append(problems["Phone"], "Phone should be in E.164 format, e.g. +6281234567890")
+	}
+
+	if r.Code == "" {
+		problems["Code"] = append(problems["Code"], "Code is required")
+	}
+
+	if len(r.Name) > 255 {
+		problems["Name"] = append(problems["Name"], "Name is too long")
+	}
+
+	return problems
+}
+
+// The refresh token is optional, when given its family is revoked too
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SetPremiumRequest struct {
+	IsPremium bool `json:"is_premium"`
+}
+
+type SuspendUserRequest struct {
+	Days   int    `json:"days"`
+	Reason string `json:"reason"`
+}
+
+func (r *SuspendUserRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Days <= 0 {
+		problems["Days"] = append(problems["Days"], "Days should be greater than 0")
+	}
+
+	if r.Reason == "" {
+		problems["Reason"] = append(problems["Reason"], "Reason is required")
+	}
+
+	return problems
+}
+
+// Every photo of the user, in the new order
+type ReorderPhotosRequest struct {
+	PhotoIDs []int `json:"photo_ids"`
+}
+
+func (r *ReorderPhotosRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if len(r.PhotoIDs) == 0 {
+		problems["PhotoIDs"] = append(problems["PhotoIDs"], "PhotoIDs is required")
+	}
+
+	return problems
+}
+
+type ApprovePhotoRequest struct {
+	Note string `json:"note"`
+}
+
+// The reason is shown to the owner of the photo
+type RejectPhotoRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *RejectPhotoRequest) Validate(ctx context.Context) (problems map[string][]string) {
+	problems = make(map[string][]string)
+
+	if r.Reason == "" {
+		problems["Reason"] = append(problems["Reason"], "Reason is required")
+	}
+
+	return problems
+}
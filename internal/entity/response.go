@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type SignUpResponse struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
@@ -16,6 +18,249 @@ type MatchGetProfileResponse struct {
 	Profiles []User `json:"profiles"`
 }
 
+// Token is the access token, kept under its original name for older clients
+// With two-factor authentication enabled sign-in only returns a challenge
+// token, the tokens come from exchanging it with a code
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in"`
+
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+
+	// The account was deleted and signing in cancelled the deletion
+	AccountRestored bool `json:"account_restored,omitempty"`
+}
+
+type AccountDeletionResponse struct {
+	// The data is erased at this time unless the user signs in again before
+	EraseAt time.Time `json:"erase_at"`
+}
+
+// DownloadURL is a signed link, a new one is made on every poll while the
+// archive is ready
+type DataExportResponse struct {
+	ID                int        `json:"id"`
+	Status            string     `json:"status"`
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	ArchiveExpiresAt  *time.Time `json:"archive_expires_at,omitempty"`
+}
+
+// Files of a data export archive, the photos, swipes, blocks, reports, devices
+// and notification preferences reuse their API responses
+
+type ExportProfile struct {
+	ID                 int        `json:"id"`
+	Name               string     `json:"name"`
+	Email              string     `json:"email,omitempty"`
+	Username           string     `json:"username"`
+	Phone              string     `json:"phone,omitempty"`
+	Role               Role       `json:"role"`
+	Status             string     `json:"status"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at,omitempty"`
+	PhoneVerifiedAt    *time.Time `json:"phone_verified_at,omitempty"`
+	PasswordChangedAt  *time.Time `json:"password_changed_at,omitempty"`
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// Premium is a flag on the user until payments are recorded
+type ExportSubscription struct {
+	IsPremium bool `json:"is_premium"`
+}
+
+type ExportMatch struct {
+	UserID int       `json:"user_id"`
+	Time   time.Time `json:"time"`
+}
+
+type ExportIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportStatusChange struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Reason         string     `json:"reason"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ExportSecurityEvent struct {
+	Event      SecurityEvent `json:"event"`
+	Identifier string        `json:"identifier"`
+	IP         string        `json:"ip"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// Shown once, only their hashes are stored
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Sent with a failed sign-in, RetryAfter is in seconds and only set while
+// the account or IP is locked
+type SignInFailureResponse struct {
+	CaptchaRequired bool `json:"captcha_required"`
+	RetryAfter      int  `json:"retry_after,omitempty"`
+}
+
+type DeviceResponse struct {
+	ID       int      `json:"id"`
+	Platform Platform `json:"platform"`
+}
+
+type NotificationPreferencesResponse struct {
+	Match     bool `json:"match"`
+	SuperLike bool `json:"super_like"`
+}
+
+type BlockResponse struct {
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BlockListResponse struct {
+	Blocks []BlockResponse `json:"blocks"`
+}
+
+type ReportResponse struct {
+	ID             int               `json:"id"`
+	ReporterID     int               `json:"reporter_id"`
+	ReportedID     int               `json:"reported_id"`
+	TargetType     ReportTargetType  `json:"target_type"`
+	TargetID       *uint             `json:"target_id,omitempty"`
+	Reason         ReportReason      `json:"reason"`
+	Details        string            `json:"details"`
+	Status         string            `json:"status"`
+	Action         *ModerationAction `json:"action,omitempty"`
+	ResolutionNote string            `json:"resolution_note"`
+	ReviewerID     *uint             `json:"reviewer_id,omitempty"`
+	ReviewedAt     *time.Time        `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+func NewReportResponse(report Report) ReportResponse {
+	return ReportResponse{
+		ID:             int(report.ID),
+		ReporterID:     int(report.ReporterID),
+		ReportedID:     int(report.ReportedID),
+		TargetType:     report.TargetType,
+		TargetID:       report.TargetID,
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         report.Status.String(),
+		Action:         report.Action,
+		ResolutionNote: report.ResolutionNote,
+		ReviewerID:     report.ReviewerID,
+		ReviewedAt:     report.ReviewedAt,
+		CreatedAt:      report.CreatedAt,
+	}
+}
+
+type ReportListResponse struct {
+	Reports []ReportResponse `json:"reports"`
+}
+
+type AdminUserResponse struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Username       string     `json:"username"`
+	IsPremium      bool       `json:"is_premium"`
+	Role           Role       `json:"role"`
+	Status         string     `json:"status"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewAdminUserResponse(user User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:             int(user.ID),
+		Name:           user.Name,
+		Email:          user.Email,
+		Username:       user.Username,
+		IsPremium:      user.IsPremium,
+		Role:           user.Role,
+		Status:         user.AccountStatusAt(time.Now()).String(),
+		SuspendedUntil: user.SuspendedUntil,
+		CreatedAt:      user.CreatedAt,
+	}
+}
+
+type SwipeResponse struct {
+	ID        int       `json:"id"`
+	ToID      int       `json:"to_id"`
+	Action    string    `json:"action"`
+	IsMatched bool      `json:"is_matched"`
+	Time      time.Time `json:"time"`
+}
+
+type SwipeHistoryResponse struct {
+	Swipes []SwipeResponse `json:"swipes"`
+}
+
+// URLs has one entry per variant, e.g. original, large, medium and small
+type PhotoResponse struct {
+	ID             int               `json:"id"`
+	Position       int               `json:"position"`
+	IsPrimary      bool              `json:"is_primary"`
+	Width          int               `json:"width"`
+	Height         int               `json:"height"`
+	Status         string            `json:"status"`
+	ModerationNote string            `json:"moderation_note,omitempty"`
+	URLs           map[string]string `json:"urls"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+func NewPhotoResponse(photo Photo) PhotoResponse {
+	return PhotoResponse{
+		ID:             int(photo.ID),
+		Position:       photo.Position,
+		IsPrimary:      photo.IsPrimary,
+		Width:          photo.Width,
+		Height:         photo.Height,
+		Status:         photo.Status.String(),
+		ModerationNote: photo.ModerationNote,
+		URLs:           photo.URLs,
+		CreatedAt:      photo.CreatedAt,
+	}
+}
+
+type PhotoListResponse struct {
+	Photos []PhotoResponse `json:"photos"`
+}
+
+// A photo in the manual review queue
+type PhotoReviewResponse struct {
+	PhotoResponse
+	UserID     int        `json:"user_id"`
+	FlaggedAt  *time.Time `json:"flagged_at,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+func NewPhotoReviewResponse(photo Photo) PhotoReviewResponse {
+	return PhotoReviewResponse{
+		PhotoResponse: NewPhotoResponse(photo),
+		UserID:        int(photo.UserID),
+		FlaggedAt:     photo.FlaggedAt,
+		ReviewedAt:    photo.ReviewedAt,
+	}
+}
+
+type PhotoReviewListResponse struct {
+	Photos []PhotoReviewResponse `json:"photos"`
 }
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Remember handled keys for longer than a message can be redelivered or
+// published again by the outbox relay
+const defaultDedupeTTL = 7 * 24 * time.Hour
+
+// Deduplicator remembers which message keys a consumer has already handled,
+// delivery is at-least-once so the same key can arrive more than once
+type Deduplicator interface {
+	Seen(ctx context.Context, key string) (bool, error)
+	MarkSeen(ctx context.Context, key string) error
+}
+
+// RedisDeduplicator keeps handled keys under :events:seen:<key>
+type RedisDeduplicator struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewRedisDeduplicator(redis *redis.Client) *RedisDeduplicator {
+	return &RedisDeduplicator{
+		rdb: redis,
+		ttl: defaultDedupeTTL,
+	}
+}
+
+func (d *RedisDeduplicator) Seen(_ context.Context, key string) (bool, error) {
+	n, err := d.rdb.Exists(seenKey(key)).Result()
+	return n > 0, err
+}
+
+func (d *RedisDeduplicator) MarkSeen(_ context.Context, key string) error {
+	return d.rdb.Set(seenKey(key), 1, d.ttl).Err()
+}
+
+// MemoryDeduplicator is the in-process counterpart for tests
+type MemoryDeduplicator struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func NewMemoryDeduplicator() *MemoryDeduplicator {
+	return &MemoryDeduplicator{seen: map[string]bool{}}
+}
+
+func (d *MemoryDeduplicator) Seen(_ context.Context, key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.seen[key], nil
+}
+
+func (d *MemoryDeduplicator) MarkSeen(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seen[key] = true
+	return nil
+}
+
+// Helper
+
+func seenKey(key string) string {
+	return ":events:seen:" + key
+}
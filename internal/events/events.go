@@ -0,0 +1,35 @@
+package events
+
+import "context"
+
+// Message is a single event on the bus. Delivery is at-least-once, consumers
+// should use Key to drop duplicates
+type Message struct {
+	ID      string
+	Key     string
+	Topic   string
+	Payload []byte
+
+	// Number of times the message has been delivered, starting at 1
+	Deliveries int
+}
+
+// Handler returning an error leaves the message unacknowledged so it is
+// delivered again, after MaxDeliveries it is moved to the dead letter stream
+type Handler func(ctx context.Context, msg Message) error
+
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Subscriber interface {
+	// Blocks until ctx is cancelled. Subscribers sharing a group split the
+	// messages of the topic between them, every group receives every message
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+}
+
+const DefaultMaxDeliveries = 5
+
+func DeadLetterTopic(topic string) string {
+	return topic + ".dead"
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryBus is an in-process Publisher and Subscriber for tests. It mirrors
+// the Redis semantics: a new group starts from the first message of the topic,
+// failed messages are redelivered and dead lettered after MaxDeliveries
+type MemoryBus struct {
+	MaxDeliveries int
+
+	mu     sync.Mutex
+	seq    int
+	topics map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	messages []Message
+	groups   map[string]*memoryQueue
+}
+
+// Unbounded so publishing never blocks, a slow or stopped group can't hold up
+// the bus or other groups
+type memoryQueue struct {
+	mu       sync.Mutex
+	messages []Message
+
+	// Signalled when messages are added, buffered so a push never waits
+	ready chan struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		MaxDeliveries: DefaultMaxDeliveries,
+		topics:        map[string]*memoryTopic{},
+	}
+}
+
+func (b *MemoryBus) Publish(_ context.Context, msg Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	msg.ID = strconv.Itoa(b.seq)
+	msg.Deliveries = 0
+
+	topic := b.getTopic(msg.Topic)
+	topic.messages = append(topic.messages, msg)
+
+	for _, queue := range topic.groups {
+		queue.push(msg)
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	queue := b.getGroup(topic, group)
+
+	for {
+		msg, ok := queue.pop()
+
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-queue.ready:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		msg.Deliveries++
+
+		if err := handler(ctx, msg); err == nil {
+			continue
+		}
+
+		if msg.Deliveries >= b.MaxDeliveries {
+			b.Publish(ctx, Message{
+				Key:     msg.Key,
+				Topic:   DeadLetterTopic(topic),
+				Payload: msg.Payload,
+			})
+			continue
+		}
+
+		queue.push(msg)
+	}
+}
+
+// Messages published to the topic so far, dead letters can be read with DeadLetterTopic
+func (b *MemoryBus) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.getTopic(topic).messages...)
+}
+
+// Helper
+
+func (b *MemoryBus) getTopic(name string) *memoryTopic {
+	topic, ok := b.topics[name]
+
+	if !ok {
+		topic = &memoryTopic{groups: map[string]*memoryQueue{}}
+		b.topics[name] = topic
+	}
+
+	return topic
+}
+
+func (b *MemoryBus) getGroup(topicName, group string) *memoryQueue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topic := b.getTopic(topicName)
+	queue, ok := topic.groups[group]
+
+	if !ok {
+		queue = &memoryQueue{ready: make(chan struct{}, 1)}
+		for _, msg := range topic.messages {
+			queue.push(msg)
+		}
+		topic.groups[group] = queue
+	}
+
+	return queue
+}
+
+func (q *memoryQueue) push(msg Message) {
+	q.mu.Lock()
+	q.messages = append(q.messages, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *memoryQueue) pop() (Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.messages) == 0 {
+		return Message{}, false
+	}
+
+	msg := q.messages[0]
+	q.messages = q.messages[1:]
+
+	return msg, true
+}
@@ -0,0 +1,283 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Bound the stream length, consumers are expected to keep up well within this
+const streamMaxLen = 100000
+
+const (
+	defaultBatchSize = 10
+	defaultBlock     = 5 * time.Second
+
+	// Pending messages idle for longer than this are claimed and retried
+	defaultRetryAfter = 30 * time.Second
+)
+
+// RedisStreamPublisher appends each topic to its own Redis stream, :events:<topic>
+type RedisStreamPublisher struct {
+	rdb *redis.Client
+}
+
+func NewRedisStreamPublisher(redis *redis.Client) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		rdb: redis,
+	}
+}
+
+func (p *RedisStreamPublisher) Publish(_ context.Context, msg Message) error {
+	return p.rdb.XAdd(&redis.XAddArgs{
+		Stream:       StreamKey(msg.Topic),
+		MaxLenApprox: streamMaxLen,
+		Values: map[string]interface{}{
+			"key":     msg.Key,
+			"payload": string(msg.Payload),
+		},
+	}).Err()
+}
+
+type RedisStreamSubscriberOptions struct {
+	// Defaults to <hostname>-<pid>
+	Consumer      string
+	BatchSize     int64
+	Block         time.Duration
+	RetryAfter    time.Duration
+	MaxDeliveries int
+}
+
+// RedisStreamSubscriber consumes topics through Redis consumer groups. Failed
+// messages stay in the pending list and are claimed again once idle for
+// RetryAfter, until they are delivered MaxDeliveries times and dead lettered
+type RedisStreamSubscriber struct {
+	rdb     *redis.Client
+	options RedisStreamSubscriberOptions
+}
+
+func NewRedisStreamSubscriber(redis *redis.Client, options RedisStreamSubscriberOptions) *RedisStreamSubscriber {
+	if options.Consumer == "" {
+		hostname, _ := os.Hostname()
+		options.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	if options.BatchSize == 0 {
+		options.BatchSize = defaultBatchSize
+	}
+
+	if options.Block == 0 {
+		options.Block = defaultBlock
+	}
+
+	if options.RetryAfter == 0 {
+		options.RetryAfter = defaultRetryAfter
+	}
+
+	if options.MaxDeliveries == 0 {
+		options.MaxDeliveries = DefaultMaxDeliveries
+	}
+
+	return &RedisStreamSubscriber{
+		rdb:     redis,
+		options: options,
+	}
+}
+
+func (s *RedisStreamSubscriber) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	stream := StreamKey(topic)
+
+	err := s.rdb.XGroupCreateMkStream(stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if err := s.retryPending(ctx, topic, group, handler); err != nil {
+			log.Println("error retrying pending events", topic, err)
+		}
+
+		streams, err := s.rdb.XReadGroup(&redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: s.options.Consumer,
+			Streams:  []string{stream, ">"},
+			Count:    s.options.BatchSize,
+			Block:    s.options.Block,
+		}).Result()
+
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			log.Println("error reading events", topic, err)
+			time.Sleep(s.options.Block)
+			continue
+		}
+
+		for _, res := range streams {
+			for _, message := range res.Messages {
+				s.handle(ctx, topic, group, handler, message, 1)
+			}
+		}
+	}
+}
+
+// Claim messages other consumers (or this one) failed to acknowledge in time.
+// The pending list is walked in pages so entries past the first batch are reached
+func (s *RedisStreamSubscriber) retryPending(ctx context.Context, topic, group string, handler Handler) error {
+	stream := StreamKey(topic)
+	start := "-"
+
+	for {
+		pending, err := s.rdb.XPendingExt(&redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  group,
+			Start:  start,
+			End:    "+",
+			Count:  s.options.BatchSize,
+		}).Result()
+
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		for _, entry := range pending {
+			if entry.Idle < s.options.RetryAfter {
+				continue
+			}
+
+			if err := s.retry(ctx, topic, group, handler, entry); err != nil {
+				return err
+			}
+		}
+
+		if int64(len(pending)) < s.options.BatchSize {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		next := nextStreamID(pending[len(pending)-1].Id)
+		if next == start {
+			return nil
+		}
+		start = next
+	}
+}
+
+func (s *RedisStreamSubscriber) retry(ctx context.Context, topic, group string, handler Handler, entry redis.XPendingExt) error {
+	claimed, err := s.rdb.XClaim(&redis.XClaimArgs{
+		Stream:   StreamKey(topic),
+		Group:    group,
+		Consumer: s.options.Consumer,
+		MinIdle:  s.options.RetryAfter,
+		Messages: []string{entry.Id},
+	}).Result()
+
+	if err != nil {
+		return err
+	}
+
+	for _, message := range claimed {
+		// The claim itself counts as a delivery
+		deliveries := int(entry.RetryCount) + 1
+
+		if deliveries > s.options.MaxDeliveries {
+			s.deadLetter(topic, group, message, deliveries-1)
+			continue
+		}
+
+		s.handle(ctx, topic, group, handler, message, deliveries)
+	}
+
+	return nil
+}
+
+func (s *RedisStreamSubscriber) handle(ctx context.Context, topic, group string, handler Handler, message redis.XMessage, deliveries int) {
+	msg := toMessage(topic, message, deliveries)
+
+	if err := handler(ctx, msg); err != nil {
+		log.Println("error handling event", topic, msg.ID, err)
+		return
+	}
+
+	if err := s.rdb.XAck(StreamKey(topic), group, message.ID).Err(); err != nil {
+		log.Println("error acknowledging event", topic, msg.ID, err)
+	}
+}
+
+func (s *RedisStreamSubscriber) deadLetter(topic, group string, message redis.XMessage, deliveries int) {
+	msg := toMessage(topic, message, deliveries)
+
+	err := s.rdb.XAdd(&redis.XAddArgs{
+		Stream:       StreamKey(DeadLetterTopic(topic)),
+		MaxLenApprox: streamMaxLen,
+		Values: map[string]interface{}{
+			"key":        msg.Key,
+			"payload":    string(msg.Payload),
+			"group":      group,
+			"source_id":  msg.ID,
+			"deliveries": deliveries,
+		},
+	}).Err()
+
+	if err != nil {
+		log.Println("error dead lettering event", topic, msg.ID, err)
+		return
+	}
+
+	if err := s.rdb.XAck(StreamKey(topic), group, message.ID).Err(); err != nil {
+		log.Println("error acknowledging dead lettered event", topic, msg.ID, err)
+	}
+}
+
+func StreamKey(topic string) string {
+	return ":events:" + topic
+}
+
+// Helper
+
+func toMessage(topic string, message redis.XMessage, deliveries int) Message {
+	key, _ := message.Values["key"].(string)
+	payload, _ := message.Values["payload"].(string)
+
+	return Message{
+		ID:         message.ID,
+		Key:        key,
+		Topic:      topic,
+		Payload:    []byte(payload),
+		Deliveries: deliveries,
+	}
+}
+
+// Smallest stream ID after id, XPENDING ranges are inclusive
+func nextStreamID(id string) string {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return id
+	}
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return id
+	}
+
+	return ms + "-" + strconv.FormatUint(n+1, 10)
+}
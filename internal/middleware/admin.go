@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/labstack/echo"
+)
+
+// AdminMiddleware only lets through users whose role is at least the required
+// role. The role is read from the stored user rather than the token, so a
+// demotion applies to tokens issued before it. It must run after JWTMiddleware
+func AdminMiddleware(required entity.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, err := authUseCase.UserFromContext(c.Request().Context())
+
+			if err != nil || !user.Role.HasAtLeast(required) {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "forbidden"})
+			}
+
+			return next(c)
+		}
+	}
+}
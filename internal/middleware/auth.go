@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/ghaniswara/dating-app/pkg/jwt"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
 	"github.com/labstack/echo"
 )
 
-func JWTMiddleware() echo.MiddlewareFunc {
+// JWTMiddleware validates the bearer token, rejects revoked tokens and users
+// whose account is no longer active, so logging out, suspending or banning
+// takes effect on existing tokens. The claims and the user are then available
+// to handlers through authUseCase.ClaimsFromContext and UserFromContext
+func JWTMiddleware(authCase authUseCase.IAuthUseCase) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -22,7 +29,33 @@ func JWTMiddleware() echo.MiddlewareFunc {
 			}
 			token := parts[1]
 
-			_, err := jwt.ValidateToken(token)
+			claims, err := authCase.ParseAccessToken(token)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "invalid token"})
+			}
+
+			ctx := c.Request().Context()
+
+			revoked, err := authCase.IsTokenRevoked(ctx, claims.UserID, claims.ID, claims.IssuedAt.Time)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to check token"})
+			}
+			if revoked {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "token revoked"})
+			}
+
+			ctx = authCase.WithClaims(ctx, claims)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			user, err := authUseCase.UserFromContext(ctx)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "invalid token"})
+			}
+
+			err = accountUseCase.CheckStatus(user, time.Now())
+			if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+			}
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "invalid token"})
 			}
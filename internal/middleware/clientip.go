@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/ghaniswara/dating-app/pkg/clientip"
+	"github.com/labstack/echo"
+)
+
+// ClientIPMiddleware resolves the client IP once per request, handlers and
+// the rate limiter read it through clientip.FromContext instead of trusting
+// forwarding headers themselves
+func ClientIPMiddleware(resolver *clientip.Resolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := clientip.WithIP(c.Request().Context(), resolver.IP(c.Request()))
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/clientip"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/ghaniswara/dating-app/pkg/ratelimit"
+	"github.com/labstack/echo"
+)
+
+// Names of the rate limit policies
+const (
+	PolicyDefault = "default"
+	PolicySignUp  = "sign-up"
+	PolicySignIn  = "sign-in"
+	PolicySwipe   = "swipe"
+	PolicyProfile = "profile"
+)
+
+type RateLimiter struct {
+	limiter  ratelimit.Limiter
+	policies map[string]ratelimit.Policy
+}
+
+// A nil limiter turns rate limiting off
+func NewRateLimiter(limiter ratelimit.Limiter, policies map[string]ratelimit.Policy) *RateLimiter {
+	return &RateLimiter{
+		limiter:  limiter,
+		policies: policies,
+	}
+}
+
+// Limit requests by the named policy. Requests are counted per user when a
+// JWTMiddleware before it set the claims and per client IP, as resolved by
+// ClientIPMiddleware, otherwise. The limit is
+// skipped when the limiter fails rather than failing the request
+func (r *RateLimiter) Limit(name string) echo.MiddlewareFunc {
+	policy, ok := r.policies[name]
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if r.limiter == nil || !ok {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			result, err := r.limiter.Allow(c.Request().Context(), policy, rateLimitKey(c, policy))
+
+			if err != nil {
+				log.Println("error checking rate limit", policy.Name, err)
+				return next(c)
+			}
+
+			reset := int(math.Ceil(result.Reset.Seconds()))
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(reset))
+
+				return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+					HTTPResponse: http_util.HTTPResponse[any]{
+						Message: "Too many requests",
+					},
+					Errors: []http_util.ErrorResponse{{Property: "request", Detail: "rate limit exceeded, try again later"}},
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func rateLimitKey(c echo.Context, policy ratelimit.Policy) string {
+	if claims, ok := authUseCase.ClaimsFromContext(c.Request().Context()); ok {
+		return ":user:" + strconv.Itoa(claims.UserID) + ":ratelimit:" + policy.Name
+	}
+
+	return ":ratelimit:" + policy.Name + ":ip:" + clientip.FromContext(c.Request().Context())
+}
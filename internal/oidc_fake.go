@@ -0,0 +1,20 @@
+//go:build oidcfake
+
+package internal
+
+import "github.com/ghaniswara/dating-app/pkg/oidc"
+
+// A local issuer signing in as whoever is asked for, development and test
+// builds only
+func newFakeOIDCProvider(redirectURL string) (*oidc.Provider, error) {
+	issuer, err := oidc.NewFakeIssuer("fake-client")
+	if err != nil {
+		return nil, err
+	}
+
+	return oidc.NewProvider(oidc.Config{
+		Issuer:      issuer.URL(),
+		ClientID:    "fake-client",
+		RedirectURL: redirectURL,
+	}, nil), nil
+}
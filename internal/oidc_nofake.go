@@ -0,0 +1,13 @@
+//go:build !oidcfake
+
+package internal
+
+import (
+	"errors"
+
+	"github.com/ghaniswara/dating-app/pkg/oidc"
+)
+
+func newFakeOIDCProvider(redirectURL string) (*oidc.Provider, error) {
+	return nil, errors.New("the fake oidc provider needs a build with -tags oidcfake")
+}
@@ -1,3 +1,264 @@
 package authRepo
 
-// TODO Separate user table containing auth data
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// Increment and start the window in one step, so the attempts on a code
+// can't be left without an expiry
+var countAttemptScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Authentication data kept apart from the user profile
+type IAuthRepo interface {
+	// gorm.ErrRecordNotFound when the user has no password
+	GetCredential(ctx context.Context, userID int) (*entity.AuthCredential, error)
+
+	// Set the password, gorm.ErrRecordNotFound when the user has no credential
+	SavePassword(ctx context.Context, userID int, password entity.PasswordHash) error
+	MarkCredentialUsed(ctx context.Context, userID int, at time.Time) error
+
+	// Create a user and the credential holding their email, username and
+	// phone, both or neither are stored. A taken identifier is returned as
+	// gorm.ErrDuplicatedKey
+	CreateUser(ctx context.Context, user *entity.User) error
+
+	// CreateUser with a password
+	CreateUserWithPassword(ctx context.Context, user *entity.User, password entity.PasswordHash) error
+
+	// gorm.ErrRecordNotFound when no user has linked the identity
+	GetIdentity(ctx context.Context, provider, subject string) (*entity.Identity, error)
+	CreateIdentity(ctx context.Context, identity *entity.Identity) error
+
+	// CreateUser with a linked identity
+	CreateUserWithIdentity(ctx context.Context, user *entity.User, identity *entity.Identity) error
+
+	SaveOIDCState(ctx context.Context, state string, data entity.OIDCState, ttl time.Duration) error
+
+	// Return and delete the state, gorm.ErrRecordNotFound when it doesn't
+	// exist or expired
+	ConsumeOIDCState(ctx context.Context, state string) (*entity.OIDCState, error)
+
+	// Replace the phone's code and its attempt count
+	SavePhoneOTP(ctx context.Context, phoneHash, codeHash string, ttl time.Duration) error
+
+	// gorm.ErrRecordNotFound when no code was sent or it expired
+	GetPhoneOTP(ctx context.Context, phoneHash string) (string, error)
+
+	// Count a verification attempt on the phone's current code
+	CountPhoneOTPAttempt(ctx context.Context, phoneHash string, ttl time.Duration) (int64, error)
+
+	// Returns false when the code was already deleted
+	DeletePhoneOTP(ctx context.Context, phoneHash string) (bool, error)
+}
+
+type AuthRepo struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewAuthRepo(db *gorm.DB, redis *redis.Client) IAuthRepo {
+	return &AuthRepo{
+		db:  db,
+		rdb: redis,
+	}
+}
+
+func (r *AuthRepo) GetCredential(ctx context.Context, userID int) (*entity.AuthCredential, error) {
+	var credential entity.AuthCredential
+	res := r.db.WithContext(ctx).Where("user_id = ? AND password_hash IS NOT NULL", userID).First(&credential)
+	return &credential, res.Error
+}
+
+func (r *AuthRepo) SavePassword(ctx context.Context, userID int, password entity.PasswordHash) error {
+	res := r.db.WithContext(ctx).
+		Model(&entity.AuthCredential{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"password_hash":       password.Hash,
+			"password_algorithm":  password.Algorithm,
+			"password_cost":       password.Cost,
+			"password_salt":       nil,
+			"password_changed_at": time.Now(),
+		})
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *AuthRepo) MarkCredentialUsed(ctx context.Context, userID int, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.AuthCredential{}).
+		Where("user_id = ?", userID).
+		Update("last_used_at", at).Error
+}
+
+func (r *AuthRepo) CreateUser(ctx context.Context, user *entity.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(newCredential(user)).Error
+	})
+}
+
+func (r *AuthRepo) CreateUserWithPassword(ctx context.Context, user *entity.User, password entity.PasswordHash) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		credential := newCredential(user)
+		credential.PasswordHash = password.Hash
+		credential.PasswordAlgorithm = password.Algorithm
+		credential.PasswordCost = password.Cost
+		credential.PasswordChangedAt = &now
+
+		return tx.Create(credential).Error
+	})
+}
+
+func (r *AuthRepo) GetIdentity(ctx context.Context, provider, subject string) (*entity.Identity, error) {
+	var identity entity.Identity
+	res := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	return &identity, res.Error
+}
+
+func (r *AuthRepo) CreateIdentity(ctx context.Context, identity *entity.Identity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *AuthRepo) CreateUserWithIdentity(ctx context.Context, user *entity.User, identity *entity.Identity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(newCredential(user)).Error; err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *AuthRepo) SaveOIDCState(_ context.Context, state string, data entity.OIDCState, ttl time.Duration) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(oidcStateKey(state), value, ttl).Err()
+}
+
+func (r *AuthRepo) ConsumeOIDCState(_ context.Context, state string) (*entity.OIDCState, error) {
+	var get *redis.StringCmd
+
+	// Read and delete together so a state is only used once
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(oidcStateKey(state))
+		pipe.Del(oidcStateKey(state))
+		return nil
+	})
+
+	if err == redis.Nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data entity.OIDCState
+	if err := json.Unmarshal([]byte(get.Val()), &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (r *AuthRepo) SavePhoneOTP(_ context.Context, phoneHash, codeHash string, ttl time.Duration) error {
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(phoneOTPKey(phoneHash), codeHash, ttl)
+		pipe.Del(phoneOTPAttemptsKey(phoneHash))
+		return nil
+	})
+
+	return err
+}
+
+func (r *AuthRepo) GetPhoneOTP(_ context.Context, phoneHash string) (string, error) {
+	codeHash, err := r.rdb.Get(phoneOTPKey(phoneHash)).Result()
+
+	if err == redis.Nil {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return codeHash, err
+}
+
+func (r *AuthRepo) CountPhoneOTPAttempt(_ context.Context, phoneHash string, ttl time.Duration) (int64, error) {
+	return countAttemptScript.Run(r.rdb, []string{phoneOTPAttemptsKey(phoneHash)}, ttl.Milliseconds()).Int64()
+}
+
+func (r *AuthRepo) DeletePhoneOTP(_ context.Context, phoneHash string) (bool, error) {
+	var deleted *redis.IntCmd
+
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(phoneOTPKey(phoneHash))
+		pipe.Del(phoneOTPAttemptsKey(phoneHash))
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return deleted.Val() == 1, nil
+}
+
+// Helper
+
+func newCredential(user *entity.User) *entity.AuthCredential {
+	return &entity.AuthCredential{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Phone:    user.Phone,
+	}
+}
+
+func oidcStateKey(state string) string {
+	return ":auth:oidc:state:" + state
+}
+
+func phoneOTPKey(phoneHash string) string {
+	return ":auth:phone-otp:" + phoneHash
+}
+
+func phoneOTPAttemptsKey(phoneHash string) string {
+	return phoneOTPKey(phoneHash) + ":attempts"
+}
@@ -0,0 +1,79 @@
+package blockRepo
+
+import (
+	"context"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IBlockRepo interface {
+	CreateBlock(ctx context.Context, userID int, blockedID int) error
+	DeleteBlock(ctx context.Context, userID int, blockedID int) error
+	GetBlocks(ctx context.Context, userID int) ([]entity.Block, error)
+
+	// IDs of users the user blocked or was blocked by
+	GetBlockRelatedIDs(ctx context.Context, userID int) ([]int, error)
+
+	// Whether either user blocked the other. Swiping checks it, there is no
+	// messaging yet, sending a message has to check it the same way once there is
+	IsBlocked(ctx context.Context, userID int, otherID int) (bool, error)
+}
+
+type BlockRepo struct {
+	db *gorm.DB
+}
+
+func NewBlockRepo(db *gorm.DB) IBlockRepo {
+	return &BlockRepo{
+		db: db,
+	}
+}
+
+// Blocking twice is a no-op
+func (r *BlockRepo) CreateBlock(ctx context.Context, userID int, blockedID int) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.Block{
+			UserID:    uint(userID),
+			BlockedID: uint(blockedID),
+		}).Error
+}
+
+func (r *BlockRepo) DeleteBlock(ctx context.Context, userID int, blockedID int) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND blocked_id = ?", userID, blockedID).
+		Delete(&entity.Block{}).Error
+}
+
+func (r *BlockRepo) GetBlocks(ctx context.Context, userID int) ([]entity.Block, error) {
+	var blocks []entity.Block
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&blocks)
+
+	return blocks, res.Error
+}
+
+func (r *BlockRepo) GetBlockRelatedIDs(ctx context.Context, userID int) ([]int, error) {
+	var ids []int
+	res := r.db.WithContext(ctx).
+		Raw(`SELECT blocked_id FROM blocks WHERE user_id = ?
+			UNION
+			SELECT user_id FROM blocks WHERE blocked_id = ?`, userID, userID).
+		Scan(&ids)
+
+	return ids, res.Error
+}
+
+func (r *BlockRepo) IsBlocked(ctx context.Context, userID int, otherID int) (bool, error) {
+	var count int64
+	res := r.db.WithContext(ctx).
+		Model(&entity.Block{}).
+		Where("(user_id = ? AND blocked_id = ?) OR (user_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count)
+
+	return count > 0, res.Error
+}
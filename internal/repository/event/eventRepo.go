@@ -0,0 +1,124 @@
+package eventRepo
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+)
+
+// Keep the last events of each user so reconnecting clients can resume
+const (
+	userEventsMaxLen = 1000
+	userEventsTTL    = 7 * 24 * time.Hour
+)
+
+type IEventRepo interface {
+	// Redis Stream per user, keyed by :user:<id>:events
+	AppendUserEvent(ctx context.Context, userID int, eventType entity.EventType, data interface{}) (string, error)
+	ReadUserEvents(ctx context.Context, userID int, lastID string, block time.Duration) ([]entity.UserEvent, error)
+
+	// Return the ID of the newest event, or "0-0" when the stream is empty
+	GetLatestUserEventID(ctx context.Context, userID int) (string, error)
+}
+
+type EventRepo struct {
+	rdb *redis.Client
+
+	// Blocking reads hold a connection for as long as they wait, they get their
+	// own pool so open streams can't starve every other Redis call
+	streamRdb *redis.Client
+}
+
+func NewEventRepo(redis *redis.Client, streamRedis *redis.Client) IEventRepo {
+	return &EventRepo{
+		rdb:       redis,
+		streamRdb: streamRedis,
+	}
+}
+
+func (r *EventRepo) AppendUserEvent(_ context.Context, userID int, eventType entity.EventType, data interface{}) (string, error) {
+	streamKey := userEventsKey(userID)
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := r.rdb.XAdd(&redis.XAddArgs{
+		Stream:       streamKey,
+		MaxLenApprox: userEventsMaxLen,
+		Values: map[string]interface{}{
+			"type": string(eventType),
+			"data": string(payload),
+		},
+	}).Result()
+
+	if err != nil {
+		return "", err
+	}
+
+	r.rdb.Expire(streamKey, userEventsTTL)
+
+	return id, nil
+}
+
+func (r *EventRepo) ReadUserEvents(_ context.Context, userID int, lastID string, block time.Duration) ([]entity.UserEvent, error) {
+	streams, err := r.streamRdb.XRead(&redis.XReadArgs{
+		Streams: []string{userEventsKey(userID), lastID},
+		Count:   100,
+		Block:   block,
+	}).Result()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var events []entity.UserEvent
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			events = append(events, toUserEvent(message))
+		}
+	}
+
+	return events, nil
+}
+
+func (r *EventRepo) GetLatestUserEventID(_ context.Context, userID int) (string, error) {
+	messages, err := r.rdb.XRevRangeN(userEventsKey(userID), "+", "-", 1).Result()
+
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// Helper
+
+func userEventsKey(userID int) string {
+	return ":user:" + strconv.Itoa(userID) + ":events"
+}
+
+func toUserEvent(message redis.XMessage) entity.UserEvent {
+	eventType, _ := message.Values["type"].(string)
+	data, _ := message.Values["data"].(string)
+
+	return entity.UserEvent{
+		ID:   message.ID,
+		Type: entity.EventType(eventType),
+		Data: json.RawMessage(data),
+	}
+}
@@ -0,0 +1,239 @@
+package exportRepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IExportRepo interface {
+	CreateExport(ctx context.Context, export *entity.DataExport) error
+
+	// The archive isn't loaded, it's read with GetArchive
+	GetExport(ctx context.Context, id int) (*entity.DataExport, error)
+	GetLatestExport(ctx context.Context, userID int) (*entity.DataExport, error)
+
+	// gorm.ErrRecordNotFound unless the export is ready
+	GetArchive(ctx context.Context, id int) ([]byte, error)
+
+	// Claim a batch of pending exports, they aren't handed out again until
+	// lease has passed
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.DataExport, error)
+
+	// Store the archive of a claimed export, false when it's no longer pending
+	CompleteExport(ctx context.Context, id uint, archive []byte, expiresAt time.Time) (bool, error)
+
+	// Count a failed build and release the claim, the export fails once it
+	// reaches maxAttempts. Returns whether it failed for good
+	FailExport(ctx context.Context, export entity.DataExport, cause error, maxAttempts int) (bool, error)
+
+	// Drop the archives of ready exports that expired before now
+	ExpireArchives(ctx context.Context, now time.Time) (int64, error)
+
+	// Everything held about the user, read from a single snapshot
+	GetPersonalData(ctx context.Context, userID int) (*entity.PersonalData, error)
+}
+
+type ExportRepo struct {
+	db *gorm.DB
+}
+
+func NewExportRepo(db *gorm.DB) IExportRepo {
+	return &ExportRepo{
+		db: db,
+	}
+}
+
+func (r *ExportRepo) CreateExport(ctx context.Context, export *entity.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+func (r *ExportRepo) GetExport(ctx context.Context, id int) (*entity.DataExport, error) {
+	var export entity.DataExport
+	res := r.db.WithContext(ctx).Omit("archive").Where("id = ?", id).First(&export)
+	return &export, res.Error
+}
+
+func (r *ExportRepo) GetLatestExport(ctx context.Context, userID int) (*entity.DataExport, error) {
+	var export entity.DataExport
+	res := r.db.WithContext(ctx).
+		Omit("archive").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&export)
+
+	return &export, res.Error
+}
+
+func (r *ExportRepo) GetArchive(ctx context.Context, id int) ([]byte, error) {
+	var export entity.DataExport
+	res := r.db.WithContext(ctx).
+		Select("archive").
+		Where("id = ? AND status = ?", id, entity.DataExportReady).
+		First(&export)
+
+	return export.Archive, res.Error
+}
+
+// The archive is built after the claim commits, so no transaction or row lock
+// is held while the personal data is read and zipped
+func (r *ExportRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.DataExport, error) {
+	var claimed []entity.DataExport
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// SKIP LOCKED lets several workers run without claiming the same export twice
+		res := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Omit("archive").
+			Where("status = ? AND (claimed_until IS NULL OR claimed_until < ?)", entity.DataExportPending, now).
+			Order("id").
+			Limit(limit).
+			Find(&claimed)
+
+		if res.Error != nil || len(claimed) == 0 {
+			return res.Error
+		}
+
+		ids := make([]uint, 0, len(claimed))
+		for _, export := range claimed {
+			ids = append(ids, export.ID)
+		}
+
+		return tx.Model(&entity.DataExport{}).
+			Where("id IN ?", ids).
+			Update("claimed_until", now.Add(lease)).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (r *ExportRepo) CompleteExport(ctx context.Context, id uint, archive []byte, expiresAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("id = ? AND status = ?", id, entity.DataExportPending).
+		Updates(map[string]interface{}{
+			"status":        entity.DataExportReady,
+			"archive":       archive,
+			"completed_at":  time.Now(),
+			"expires_at":    expiresAt,
+			"claimed_until": nil,
+		})
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *ExportRepo) FailExport(ctx context.Context, export entity.DataExport, cause error, maxAttempts int) (bool, error) {
+	failed := export.Attempts+1 >= maxAttempts
+
+	status := entity.DataExportPending
+	if failed {
+		status = entity.DataExportFailed
+	}
+
+	res := r.db.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("id = ? AND status = ?", export.ID, entity.DataExportPending).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"attempts":      gorm.Expr("attempts + 1"),
+			"last_error":    cause.Error(),
+			"claimed_until": nil,
+		})
+
+	return failed, res.Error
+}
+
+func (r *ExportRepo) ExpireArchives(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("status = ? AND expires_at < ?", entity.DataExportReady, now).
+		Updates(map[string]interface{}{
+			"status":  entity.DataExportExpired,
+			"archive": nil,
+		})
+
+	return res.RowsAffected, res.Error
+}
+
+func (r *ExportRepo) GetPersonalData(ctx context.Context, userID int) (*entity.PersonalData, error) {
+	var data entity.PersonalData
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", userID).First(&data.User).Error; err != nil {
+			return err
+		}
+
+		lists := []struct {
+			dest  interface{}
+			query string
+			order string
+		}{
+			{&data.Identities, "user_id = ?", "created_at"},
+			{&data.Photos, "user_id = ?", "position"},
+			{&data.Swipes, "user_id = ?", "timestamp"},
+			{&data.Blocks, "user_id = ?", "created_at"},
+			{&data.Reports, "reporter_id = ?", "created_at"},
+			{&data.Devices, "user_id = ?", "created_at"},
+			{&data.AccountStatusAudits, "user_id = ?", "created_at"},
+			{&data.SecurityAuditLogs, "user_id = ?", "created_at"},
+		}
+
+		for _, list := range lists {
+			if err := tx.Where(list.query, userID).Order(list.order).Find(list.dest).Error; err != nil {
+				return err
+			}
+		}
+
+		liked := []entity.Action{entity.ActionLike, entity.ActionSuperLike}
+		err := tx.Table("swipe_transactions AS swipe").
+			Select("swipe.to_id AS user_id, MIN(GREATEST(swipe.timestamp, other.timestamp)) AS time").
+			Joins("JOIN swipe_transactions AS other ON other.user_id = swipe.to_id AND other.to_id = swipe.user_id").
+			Where("swipe.user_id = ? AND swipe.action IN ? AND other.action IN ?", userID, liked, liked).
+			Group("swipe.to_id").
+			Order("time").
+			Scan(&data.Matches).Error
+		if err != nil {
+			return err
+		}
+
+		var preference entity.NotificationPreference
+		if err := tx.Where("user_id = ?", userID).First(&preference).Error; err == nil {
+			data.NotificationPreference = &preference
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Erased users have no credential
+		var credential entity.AuthCredential
+		if err := tx.Where("user_id = ?", userID).First(&credential).Error; err == nil {
+			data.User.Email = credential.Email
+			data.User.Username = credential.Username
+			data.User.Phone = credential.Phone
+			data.PasswordChangedAt = credential.PasswordChangedAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var totp entity.TOTPCredential
+		if err := tx.Where("user_id = ?", userID).First(&totp).Error; err == nil {
+			data.TwoFactorEnabledAt = totp.EnabledAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	return &data, err
+}
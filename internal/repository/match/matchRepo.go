@@ -10,6 +10,7 @@ import (
 	"github.com/go-redis/redis"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IMatchRepo interface {
@@ -28,8 +29,23 @@ type IMatchRepo interface {
 	GetSwipedProfilesIDs(ctx context.Context, userID int, date *time.Time) ([]entity.SwipeTransaction, error)
 
 	CreateSwipe(ctx context.Context, userID int, likedToUserID int, action entity.Action) (Outcome entity.Outcome, err error)
+
+	// Latest swipes made by the user first
+	GetSwipeHistory(ctx context.Context, userID int, limit int, offset int) ([]entity.SwipeTransaction, error)
+
+	// Unmatch both users, used when either of them blocks the other
+	EndMatch(ctx context.Context, userID int, otherID int) error
+
+	// Unmatch the user from everyone, used when the account is deleted
+	EndAllMatches(ctx context.Context, userID int) error
+
+	// Reset today's like count to zero, profiles liked today stay excluded
+	ResetTodayLikesCount(ctx context.Context, userID int) error
 }
 
+// Active users, including those whose suspension already ended
+const activeUserCondition = "(status = ? OR (status = ? AND suspended_until <= NOW()))"
+
 type MatchRepo struct {
 	db  *gorm.DB
 	rdb *redis.Client
@@ -113,17 +129,25 @@ func (m *MatchRepo) GetDatingProfiles(ctx context.Context, userID int, excludePr
 		Model(&entity.User{}).
 		Select("id").
 		Where("id NOT IN ?", append(excludeProfiles, userID)).
+		Where(activeUserCondition, entity.AccountActive, entity.AccountSuspended).
+		Where("(email_verified_at IS NOT NULL OR phone_verified_at IS NOT NULL)").
 		Order("RANDOM()").
 		Limit(limit + 10)
 
 	res := m.db.WithContext(ctx).
 		Model(&entity.User{}).
+		// Photos are only shown to other users once approved
+		Preload("Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Where("status = ?", entity.PhotoApproved).Order("position")
+		}).
 		Where("id IN (?)", subquery).
 		Find(&profiles)
 
 	return profiles, res.Error
 }
 
+// The swipe, the pair update and the outbox events are written in a single
+// transaction, caches are only updated once it commits
 func (m *MatchRepo) CreateSwipe(ctx context.Context, userID int, likedToUserID int, action entity.Action) (entity.Outcome, error) {
 	var pair *entity.SwipeTransaction
 	// Check if liked profile exists
@@ -132,6 +156,7 @@ func (m *MatchRepo) CreateSwipe(ctx context.Context, userID int, likedToUserID i
 		WithContext(ctx).
 		Model(&entity.User{}).
 		Where("id = ?", likedToUserID).
+		Where(activeUserCondition, entity.AccountActive, entity.AccountSuspended).
 		First(&user)
 
 	if likedProfileRes.Error != nil {
@@ -144,9 +169,6 @@ func (m *MatchRepo) CreateSwipe(ctx context.Context, userID int, likedToUserID i
 
 	// Check if both profile like each other
 	if action == entity.ActionLike || action == entity.ActionSuperLike {
-		m.appendLikedCountCacheToday(ctx, userID, 1)
-		m.appendLikedProfilesCacheToday(ctx, userID, []int{likedToUserID})
-
 		resPair := m.db.WithContext(ctx).
 			Model(&entity.SwipeTransaction{}).
 			Where("user_id = ? AND to_id = ? AND action = ?", likedToUserID, userID, entity.ActionLike).
@@ -157,43 +179,90 @@ func (m *MatchRepo) CreateSwipe(ctx context.Context, userID int, likedToUserID i
 		}
 	}
 
+	isPairFound := pair != nil && pair.ID != 0
+
+	outcome := entity.OutcomeNoLike
+
+	if isPairFound && (action == entity.ActionLike || action == entity.ActionSuperLike) {
+		outcome = entity.OutcomeMatch
+	}
+
+	if isPairFound && action == entity.ActionPass {
+		outcome = entity.OutcomeMissed
+	}
+
 	// Create like transaction for the user
 	isMatched := action == entity.ActionLike && pair.ID != 0
+	now := time.Now()
 
-	res := m.db.WithContext(ctx).
-		Model(&entity.SwipeTransaction{}).
-		Create(&entity.SwipeTransaction{
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		swipe := entity.SwipeTransaction{
 			UserID:    uint(userID),
 			ToID:      uint(likedToUserID),
-			Date:      time.Now(),
+			Date:      now,
 			Action:    action,
-			Time:      time.Now(),
+			Time:      now,
 			IsMatched: isMatched,
+		}
+
+		if err := tx.Model(&entity.SwipeTransaction{}).Create(&swipe).Error; err != nil {
+			return err
+		}
+
+		// update the pair to isMatched if both profile like each other
+		if pair != nil && action == entity.ActionLike {
+			res := tx.Model(&entity.SwipeTransaction{}).Where("user_id = ? AND to_id = ?", likedToUserID, userID).Update("is_matched", true)
+			if res.Error != nil {
+				return res.Error
+			}
+		}
+
+		swipeCreated, err := entity.NewOutboxEvent(entity.TopicSwipeCreated, entity.SwipeCreatedEvent{
+			SwipeID: swipe.ID,
+			UserID:  userID,
+			ToID:    likedToUserID,
+			Action:  action,
+			Outcome: outcome,
+			Time:    now,
 		})
+		if err != nil {
+			return err
+		}
 
-	if res.Error != nil {
-		return 0, res.Error
-	}
-	// update the pair to isMatched if both profile like each other
-	if pair != nil && action == entity.ActionLike {
-		res := m.db.WithContext(ctx).Model(&entity.SwipeTransaction{}).Where("user_id = ? AND to_id = ?", likedToUserID, userID).Update("is_matched", true)
-		if res.Error != nil {
-			return 0, res.Error
+		if err := tx.Create(swipeCreated).Error; err != nil {
+			return err
 		}
-	}
 
-	isPairFound := pair != nil && pair.ID != 0
+		if outcome != entity.OutcomeMatch {
+			return nil
+		}
 
-	if isPairFound && (action == entity.ActionLike || action == entity.ActionSuperLike) {
-		m.appendMatchProfilesCache(ctx, userID, []int{likedToUserID})
-		return entity.OutcomeMatch, nil
+		matchCreated, err := entity.NewOutboxEvent(entity.TopicMatchCreated, entity.MatchCreatedEvent{
+			UserID:        userID,
+			MatchedUserID: likedToUserID,
+			Time:          now,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(matchCreated).Error
+	})
+
+	if err != nil {
+		return 0, err
 	}
 
-	if isPairFound && action == entity.ActionPass {
-		return entity.OutcomeMissed, nil
+	if action == entity.ActionLike || action == entity.ActionSuperLike {
+		m.appendLikedCountCacheToday(ctx, userID, 1)
+		m.appendLikedProfilesCacheToday(ctx, userID, []int{likedToUserID})
+	}
+
+	if outcome == entity.OutcomeMatch {
+		m.appendMatchProfilesCache(ctx, userID, []int{likedToUserID})
 	}
 
-	return entity.OutcomeNoLike, nil
+	return outcome, nil
 }
 
 func (m *MatchRepo) GetMatchedProfilesIDs(ctx context.Context, userID int) ([]int, error) {
@@ -247,6 +316,70 @@ func (m *MatchRepo) GetSwipedProfilesIDs(ctx context.Context, userID int, date *
 	return profiles, res.Error
 }
 
+func (m *MatchRepo) GetSwipeHistory(ctx context.Context, userID int, limit int, offset int) ([]entity.SwipeTransaction, error) {
+	var swipes []entity.SwipeTransaction
+	res := m.db.WithContext(ctx).
+		Model(&entity.SwipeTransaction{}).
+		Where("user_id = ?", userID).
+		Order("timestamp DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&swipes)
+
+	return swipes, res.Error
+}
+
+// The count is only reset in the cache, if the key is lost it is recounted
+// from today's swipes like any other cache miss
+func (m *MatchRepo) ResetTodayLikesCount(_ context.Context, userID int) error {
+	countKey := ":user:" + strconv.Itoa(userID) + ":likes:count"
+
+	return m.rdb.Set(countKey, 0, getTTL()).Err()
+}
+
+func (m *MatchRepo) EndMatch(ctx context.Context, userID int, otherID int) error {
+	res := m.db.WithContext(ctx).
+		Model(&entity.SwipeTransaction{}).
+		Where("(user_id = ? AND to_id = ?) OR (user_id = ? AND to_id = ?)", userID, otherID, otherID, userID).
+		Where("is_matched = ?", true).
+		Update("is_matched", false)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	m.removeMatchProfilesCache(ctx, userID, otherID)
+	m.removeMatchProfilesCache(ctx, otherID, userID)
+
+	return nil
+}
+
+func (m *MatchRepo) EndAllMatches(ctx context.Context, userID int) error {
+	var swipes []entity.SwipeTransaction
+
+	res := m.db.WithContext(ctx).
+		Model(&swipes).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}, {Name: "to_id"}}}).
+		Where("(user_id = ? OR to_id = ?) AND is_matched = ?", userID, userID, true).
+		Update("is_matched", false)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	for _, swipe := range swipes {
+		if int(swipe.UserID) != userID {
+			m.removeMatchProfilesCache(ctx, int(swipe.UserID), userID)
+		}
+	}
+
+	if err := m.rdb.Del(":user:" + strconv.Itoa(userID) + ":match:profiles").Err(); err != nil {
+		log.Println("error removing match profiles from redis", err)
+	}
+
+	return nil
+}
+
 // Private functions
 
 func (m *MatchRepo) getLikesCount(ctx context.Context, userID int, date time.Time) (int, error) {
@@ -308,6 +441,17 @@ func (m *MatchRepo) appendMatchProfilesCache(_ context.Context, userID int, newP
 	return nil
 }
 
+func (m *MatchRepo) removeMatchProfilesCache(_ context.Context, userID int, profileID int) error {
+	profilesKey := ":user:" + strconv.Itoa(userID) + ":match:profiles"
+
+	if err := m.rdb.SRem(profilesKey, profileID).Err(); err != nil {
+		log.Println("error removing match profile from redis", err)
+		return err
+	}
+
+	return nil
+}
+
 // Helper
 
 func getTTL() time.Duration {
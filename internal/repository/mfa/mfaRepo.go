@@ -0,0 +1,142 @@
+package mfaRepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IMFARepo interface {
+	// gorm.ErrRecordNotFound when the user never enrolled
+	GetTOTPCredential(ctx context.Context, userID int) (*entity.TOTPCredential, error)
+
+	// Store a pending credential, replacing a previous pending one
+	SaveTOTPCredential(ctx context.Context, credential *entity.TOTPCredential) error
+
+	// Enable the credential with the step of the confirming code and replace
+	// the recovery codes
+	EnableTOTP(ctx context.Context, userID int, step int64, codes []entity.RecoveryCode) error
+	DeleteTOTP(ctx context.Context, userID int) error
+
+	// Accept a code's step only when it's after the last accepted one
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+
+	// Sign-in challenges waiting for a code, kept in Redis until they expire
+	CreateChallenge(ctx context.Context, tokenHash string, userID int, ttl time.Duration) error
+
+	// gorm.ErrRecordNotFound when the challenge doesn't exist or expired
+	GetChallenge(ctx context.Context, tokenHash string) (int, error)
+
+	// Returns false when the challenge was already deleted
+	DeleteChallenge(ctx context.Context, tokenHash string) (bool, error)
+}
+
+type MFARepo struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewMFARepo(db *gorm.DB, redis *redis.Client) IMFARepo {
+	return &MFARepo{
+		db:  db,
+		rdb: redis,
+	}
+}
+
+func (r *MFARepo) GetTOTPCredential(ctx context.Context, userID int) (*entity.TOTPCredential, error) {
+	var credential entity.TOTPCredential
+	res := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&credential)
+	return &credential, res.Error
+}
+
+func (r *MFARepo) SaveTOTPCredential(ctx context.Context, credential *entity.TOTPCredential) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_used_step", "updated_at"}),
+		}).
+		Create(credential).Error
+}
+
+func (r *MFARepo) EnableTOTP(ctx context.Context, userID int, step int64, codes []entity.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entity.TOTPCredential{}).
+			Where("user_id = ? AND enabled_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"enabled_at":     time.Now(),
+				"last_used_step": step,
+			})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *MFARepo) DeleteTOTP(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&entity.TOTPCredential{}).Error
+	})
+}
+
+func (r *MFARepo) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.TOTPCredential{}).
+		Where("user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *MFARepo) CreateChallenge(_ context.Context, tokenHash string, userID int, ttl time.Duration) error {
+	return r.rdb.Set(challengeKey(tokenHash), userID, ttl).Err()
+}
+
+func (r *MFARepo) GetChallenge(_ context.Context, tokenHash string) (int, error) {
+	userID, err := r.rdb.Get(challengeKey(tokenHash)).Int()
+
+	if err == redis.Nil {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	return userID, err
+}
+
+func (r *MFARepo) DeleteChallenge(_ context.Context, tokenHash string) (bool, error) {
+	deleted, err := r.rdb.Del(challengeKey(tokenHash)).Result()
+	return deleted == 1, err
+}
+
+// Helper
+
+func challengeKey(tokenHash string) string {
+	return ":auth:mfa-challenge:" + tokenHash
+}
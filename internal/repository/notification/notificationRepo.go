@@ -0,0 +1,273 @@
+package notificationRepo
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	pushQueueKey      = ":notification:queue"
+	pushRetryKey      = ":notification:retry"
+	pushDeadLetterKey = ":notification:dead"
+
+	// Dequeued jobs stay in the processing list until they're acknowledged,
+	// the claimed set records when the reaper first saw each of them
+	pushProcessingKey = ":notification:processing"
+	pushClaimedKey    = ":notification:claimed"
+
+	pushDeadLetterMaxLen = 1000
+
+	// Enqueued job IDs are remembered for as long as the event that caused
+	// them can be redelivered
+	pushEnqueuedTTL = 7 * 24 * time.Hour
+)
+
+// Queue the job unless its ID was queued before, the marker and the job are
+// written together so a failure leaves neither
+var enqueueOnceScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], 1, 'NX', 'PX', ARGV[2]) then
+	return 0
+end
+redis.call('LPUSH', KEYS[2], ARGV[1])
+return 1
+`)
+
+// Move due retries back to the ready queue in one step, a job is never in
+// both or in neither
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, job in ipairs(due) do
+	redis.call('ZREM', KEYS[1], job)
+	redis.call('LPUSH', KEYS[2], job)
+end
+return #due
+`)
+
+// Jobs still processing after the lease belonged to a dispatcher that died,
+// they go back to the ready queue
+var requeueScript = redis.NewScript(`
+local requeued = 0
+for _, job in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	local seen = redis.call('ZSCORE', KEYS[2], job)
+	if not seen then
+		redis.call('ZADD', KEYS[2], ARGV[1], job)
+	elseif tonumber(seen) <= tonumber(ARGV[1]) - tonumber(ARGV[2]) then
+		redis.call('LREM', KEYS[1], 1, job)
+		redis.call('ZREM', KEYS[2], job)
+		redis.call('LPUSH', KEYS[3], job)
+		requeued = requeued + 1
+	end
+end
+return requeued
+`)
+
+type INotificationRepo interface {
+	// Device Table
+	UpsertDevice(ctx context.Context, device *entity.Device) (*entity.Device, error)
+	GetDevicesByUserID(ctx context.Context, userID int) ([]entity.Device, error)
+	DeleteDevice(ctx context.Context, userID int, token string) error
+	DeleteDeviceByToken(ctx context.Context, token string) error
+
+	// NotificationPreference Table
+	GetPreference(ctx context.Context, userID int) (*entity.NotificationPreference, error)
+	SavePreference(ctx context.Context, preference *entity.NotificationPreference) error
+
+	// Dispatch queue in Redis, a list of ready jobs plus a sorted set of jobs waiting for retry.
+	// A job with an ID is queued once, false when it already was. Jobs without one get a new ID
+	EnqueuePush(ctx context.Context, job entity.PushJob) (bool, error)
+
+	// The job moves to the processing list until it's acknowledged, retried or
+	// dead lettered
+	DequeuePush(ctx context.Context, timeout time.Duration) (*entity.PushJob, error)
+	AckPush(ctx context.Context, job entity.PushJob) error
+	SchedulePushRetry(ctx context.Context, job entity.PushJob, at time.Time) error
+	PromoteDuePushRetries(ctx context.Context, now time.Time) (int, error)
+	DeadLetterPush(ctx context.Context, job entity.PushJob) error
+
+	// Put jobs processing for longer than lease back in the ready queue
+	RequeueStalePushes(ctx context.Context, now time.Time, lease time.Duration) (int, error)
+}
+
+type NotificationRepo struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewNotificationRepo(db *gorm.DB, redis *redis.Client) INotificationRepo {
+	return &NotificationRepo{
+		db:  db,
+		rdb: redis,
+	}
+}
+
+// A token belongs to a single device, re-registering moves it to the new user
+func (r *NotificationRepo) UpsertDevice(ctx context.Context, device *entity.Device) (*entity.Device, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform"}),
+		}).
+		Create(device)
+
+	return device, res.Error
+}
+
+func (r *NotificationRepo) GetDevicesByUserID(ctx context.Context, userID int) ([]entity.Device, error) {
+	var devices []entity.Device
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Find(&devices)
+
+	return devices, res.Error
+}
+
+func (r *NotificationRepo) DeleteDevice(ctx context.Context, userID int, token string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND token = ?", userID, token).
+		Delete(&entity.Device{}).Error
+}
+
+func (r *NotificationRepo) DeleteDeviceByToken(ctx context.Context, token string) error {
+	return r.db.WithContext(ctx).
+		Where("token = ?", token).
+		Delete(&entity.Device{}).Error
+}
+
+func (r *NotificationRepo) GetPreference(ctx context.Context, userID int) (*entity.NotificationPreference, error) {
+	var preference entity.NotificationPreference
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&preference)
+
+	if res.Error == gorm.ErrRecordNotFound {
+		preference = entity.DefaultNotificationPreference(uint(userID))
+		return &preference, nil
+	}
+
+	return &preference, res.Error
+}
+
+func (r *NotificationRepo) SavePreference(ctx context.Context, preference *entity.NotificationPreference) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"match", "super_like"}),
+		}).
+		Create(preference).Error
+}
+
+func (r *NotificationRepo) EnqueuePush(_ context.Context, job entity.PushJob) (bool, error) {
+	if job.ID == "" {
+		job.ID = strconv.FormatUint(uint64(job.DeviceID), 10) + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+
+	enqueued, err := enqueueOnceScript.Run(r.rdb, []string{pushEnqueuedKey(job.ID), pushQueueKey}, payload, pushEnqueuedTTL.Milliseconds()).Int64()
+	return enqueued == 1, err
+}
+
+// Returns nil job when nothing arrived before the timeout
+func (r *NotificationRepo) DequeuePush(_ context.Context, timeout time.Duration) (*entity.PushJob, error) {
+	payload, err := r.rdb.BRPopLPush(pushQueueKey, pushProcessingKey, timeout).Result()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var job entity.PushJob
+	if err := json.Unmarshal([]byte(payload), &job); err != nil {
+		// Left in the processing list it would be requeued forever
+		r.rdb.LRem(pushProcessingKey, 1, payload)
+		return nil, err
+	}
+
+	job.Receipt = payload
+
+	return &job, nil
+}
+
+func (r *NotificationRepo) AckPush(_ context.Context, job entity.PushJob) error {
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		ack(pipe, job)
+		return nil
+	})
+
+	return err
+}
+
+// The retry is scheduled in the same transaction that acknowledges the attempt
+func (r *NotificationRepo) SchedulePushRetry(_ context.Context, job entity.PushJob, at time.Time) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(pushRetryKey, redis.Z{
+			Score:  float64(at.Unix()),
+			Member: payload,
+		})
+		ack(pipe, job)
+		return nil
+	})
+
+	return err
+}
+
+func (r *NotificationRepo) PromoteDuePushRetries(_ context.Context, now time.Time) (int, error) {
+	promoted, err := promoteScript.Run(r.rdb, []string{pushRetryKey, pushQueueKey}, now.Unix()).Int()
+	return promoted, err
+}
+
+func (r *NotificationRepo) DeadLetterPush(_ context.Context, job entity.PushJob) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LPush(pushDeadLetterKey, payload)
+		pipe.LTrim(pushDeadLetterKey, 0, pushDeadLetterMaxLen-1)
+		ack(pipe, job)
+		return nil
+	})
+
+	return err
+}
+
+func (r *NotificationRepo) RequeueStalePushes(_ context.Context, now time.Time, lease time.Duration) (int, error) {
+	requeued, err := requeueScript.Run(
+		r.rdb,
+		[]string{pushProcessingKey, pushClaimedKey, pushQueueKey},
+		now.Unix(),
+		int64(lease.Seconds()),
+	).Int()
+
+	return requeued, err
+}
+
+// Helper
+
+func ack(pipe redis.Pipeliner, job entity.PushJob) {
+	pipe.LRem(pushProcessingKey, 1, job.Receipt)
+	pipe.ZRem(pushClaimedKey, job.Receipt)
+}
+
+func pushEnqueuedKey(jobID string) string {
+	return ":notification:enqueued:" + jobID
+}
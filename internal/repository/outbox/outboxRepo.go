@@ -0,0 +1,130 @@
+package outboxRepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IOutboxRepo interface {
+	// Claim a batch of unpublished events oldest first, they aren't handed out again
+	// until lease has passed. Events waiting for a retry are left out, later
+	// events don't wait behind them
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id uint) error
+
+	// Count a failed attempt, the event is retried from retryAt or dead lettered
+	// once it reaches maxAttempts. Returns whether it was dead lettered
+	MarkFailed(ctx context.Context, event entity.OutboxEvent, cause error, retryAt time.Time, maxAttempts int) (bool, error)
+
+	// Hand claimed events out again right away, used for the rest of a batch
+	// after a failure
+	ReleaseClaims(ctx context.Context, ids []uint) error
+
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OutboxRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepo(db *gorm.DB) IOutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+// Publishing happens after the claim commits, so no transaction or row lock is
+// held while the event bus is called
+func (r *OutboxRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error) {
+	var claimed []entity.OutboxEvent
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// SKIP LOCKED lets several relays run without claiming the same row twice
+		res := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND dead_at IS NULL").
+			Where("available_at IS NULL OR available_at <= ?", now).
+			Order("id").
+			Limit(limit).
+			Find(&claimed)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(claimed))
+		for _, event := range claimed {
+			ids = append(ids, event.ID)
+		}
+
+		return tx.Model(&entity.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("available_at", now.Add(lease)).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (r *OutboxRepo) MarkPublished(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published_at": time.Now(),
+			"available_at": nil,
+		}).Error
+}
+
+func (r *OutboxRepo) MarkFailed(ctx context.Context, event entity.OutboxEvent, cause error, retryAt time.Time, maxAttempts int) (bool, error) {
+	updates := map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   cause.Error(),
+		"available_at": retryAt,
+	}
+
+	dead := event.Attempts+1 >= maxAttempts
+	if dead {
+		updates["dead_at"] = time.Now()
+		updates["available_at"] = nil
+	}
+
+	res := r.db.WithContext(ctx).
+		Model(&entity.OutboxEvent{}).
+		Where("id = ?", event.ID).
+		Updates(updates)
+
+	return dead, res.Error
+}
+
+func (r *OutboxRepo) ReleaseClaims(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&entity.OutboxEvent{}).
+		Where("id IN ? AND published_at IS NULL", ids).
+		Update("available_at", nil).Error
+}
+
+func (r *OutboxRepo) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("published_at < ?", before).
+		Delete(&entity.OutboxEvent{})
+
+	return res.RowsAffected, res.Error
+}
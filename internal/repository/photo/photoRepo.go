@@ -0,0 +1,327 @@
+package photoRepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IPhotoRepo interface {
+	// Add the photo after the user's last one, the first photo becomes the
+	// primary. Returns false without storing it when the user already has maxPhotos
+	CreatePhoto(ctx context.Context, photo *entity.Photo, maxPhotos int) (bool, error)
+
+	// Ordered by position
+	GetPhotos(ctx context.Context, userID int) ([]entity.Photo, error)
+
+	// gorm.ErrRecordNotFound when the photo isn't the user's
+	GetPhoto(ctx context.Context, userID int, photoID int) (*entity.Photo, error)
+
+	// Position the photos in the order of photoIDs
+	ReorderPhotos(ctx context.Context, userID int, photoIDs []int) error
+
+	// gorm.ErrRecordNotFound when the photo isn't the user's
+	SetPrimaryPhoto(ctx context.Context, userID int, photoID int) error
+
+	// Delete the photo and close the gap it leaves, the first remaining photo
+	// becomes the primary when it was. gorm.ErrRecordNotFound when the photo isn't the user's
+	DeletePhoto(ctx context.Context, userID int, photoID int) (*entity.Photo, error)
+
+	DeleteUserPhotos(ctx context.Context, userID int) error
+
+	// Claim a batch of photos waiting for the moderator, they aren't handed out
+	// again until lease has passed
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.Photo, error)
+
+	// Store the moderator's decision on a claimed photo. An approved or rejected
+	// status is the decision, pending flags the photo for a reviewer. Returns
+	// false when the photo was deleted or reviewed in the meantime
+	SaveModeration(ctx context.Context, photoID uint, status entity.PhotoStatus, note string) (bool, error)
+
+	// Count a failed moderation and release the claim, the photo is flagged for
+	// a reviewer once it reaches maxAttempts. Returns whether it was flagged
+	FailModeration(ctx context.Context, photo entity.Photo, cause error, maxAttempts int) (bool, error)
+
+	// Flagged photos waiting for a reviewer, oldest flag first
+	GetReviewQueue(ctx context.Context, limit int, offset int) ([]entity.Photo, error)
+
+	// gorm.ErrRecordNotFound when the photo doesn't exist
+	GetPhotoByID(ctx context.Context, photoID int) (*entity.Photo, error)
+
+	// Returns false without changing it when the photo isn't pending anymore
+	ReviewPhoto(ctx context.Context, photoID int, status entity.PhotoStatus, note string, reviewedAt time.Time) (bool, error)
+}
+
+type PhotoRepo struct {
+	db *gorm.DB
+}
+
+func NewPhotoRepo(db *gorm.DB) IPhotoRepo {
+	return &PhotoRepo{
+		db: db,
+	}
+}
+
+func (r *PhotoRepo) CreatePhoto(ctx context.Context, photo *entity.Photo, maxPhotos int) (bool, error) {
+	created := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Uploads of the same user wait for each other so positions don't collide
+		res := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", photo.UserID).
+			First(&entity.User{})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		var count int64
+		if err := tx.Model(&entity.Photo{}).Where("user_id = ?", photo.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count >= int64(maxPhotos) {
+			return nil
+		}
+
+		photo.Position = int(count)
+		photo.IsPrimary = count == 0
+
+		if err := tx.Create(photo).Error; err != nil {
+			return err
+		}
+
+		created = true
+		return nil
+	})
+
+	return created, err
+}
+
+func (r *PhotoRepo) GetPhotos(ctx context.Context, userID int) ([]entity.Photo, error) {
+	var photos []entity.Photo
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("position").
+		Find(&photos)
+
+	return photos, res.Error
+}
+
+func (r *PhotoRepo) GetPhoto(ctx context.Context, userID int, photoID int) (*entity.Photo, error) {
+	var photo entity.Photo
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", photoID, userID).First(&photo)
+	return &photo, res.Error
+}
+
+func (r *PhotoRepo) ReorderPhotos(ctx context.Context, userID int, photoIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position, photoID := range photoIDs {
+			res := tx.Model(&entity.Photo{}).
+				Where("id = ? AND user_id = ?", photoID, userID).
+				Update("position", position)
+
+			if res.Error != nil {
+				return res.Error
+			}
+
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *PhotoRepo) SetPrimaryPhoto(ctx context.Context, userID int, photoID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entity.Photo{}).
+			Where("user_id = ? AND is_primary AND id <> ?", userID, photoID).
+			Update("is_primary", false)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		res = tx.Model(&entity.Photo{}).
+			Where("id = ? AND user_id = ?", photoID, userID).
+			Update("is_primary", true)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *PhotoRepo) DeletePhoto(ctx context.Context, userID int, photoID int) (*entity.Photo, error) {
+	var photos []entity.Photo
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Clauses(clause.Returning{}).
+			Where("id = ? AND user_id = ?", photoID, userID).
+			Delete(&photos)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if len(photos) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		res = tx.Model(&entity.Photo{}).
+			Where("user_id = ? AND position > ?", userID, photos[0].Position).
+			Update("position", gorm.Expr("position - 1"))
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if !photos[0].IsPrimary {
+			return nil
+		}
+
+		return tx.Model(&entity.Photo{}).
+			Where("user_id = ? AND position = 0", userID).
+			Update("is_primary", true).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &photos[0], nil
+}
+
+func (r *PhotoRepo) DeleteUserPhotos(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.Photo{}).Error
+}
+
+// The moderator is called after the claim commits, so no transaction or row
+// lock is held while the photo is read and judged
+func (r *PhotoRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entity.Photo, error) {
+	var claimed []entity.Photo
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// SKIP LOCKED lets several workers run without claiming the same photo twice
+		res := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND flagged_at IS NULL", entity.PhotoPending).
+			Where("(claimed_until IS NULL OR claimed_until < ?)", now).
+			Order("id").
+			Limit(limit).
+			Find(&claimed)
+
+		if res.Error != nil || len(claimed) == 0 {
+			return res.Error
+		}
+
+		ids := make([]uint, 0, len(claimed))
+		for _, photo := range claimed {
+			ids = append(ids, photo.ID)
+		}
+
+		return tx.Model(&entity.Photo{}).
+			Where("id IN ?", ids).
+			Update("claimed_until", now.Add(lease)).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (r *PhotoRepo) SaveModeration(ctx context.Context, photoID uint, status entity.PhotoStatus, note string) (bool, error) {
+	now := time.Now()
+
+	updates := map[string]interface{}{
+		"status":          status,
+		"moderation_note": note,
+		"reviewed_at":     now,
+		"claimed_until":   nil,
+	}
+
+	if status == entity.PhotoPending {
+		updates = map[string]interface{}{
+			"moderation_note": note,
+			"flagged_at":      now,
+			"claimed_until":   nil,
+		}
+	}
+
+	res := r.db.WithContext(ctx).
+		Model(&entity.Photo{}).
+		Where("id = ? AND status = ? AND flagged_at IS NULL", photoID, entity.PhotoPending).
+		Updates(updates)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *PhotoRepo) FailModeration(ctx context.Context, photo entity.Photo, cause error, maxAttempts int) (bool, error) {
+	updates := map[string]interface{}{
+		"moderation_attempts": gorm.Expr("moderation_attempts + 1"),
+		"claimed_until":       nil,
+	}
+
+	// Left to a reviewer rather than retried forever
+	flagged := photo.ModerationAttempts+1 >= maxAttempts
+	if flagged {
+		updates["moderation_note"] = "moderation failed: " + cause.Error()
+		updates["flagged_at"] = time.Now()
+	}
+
+	res := r.db.WithContext(ctx).
+		Model(&entity.Photo{}).
+		Where("id = ? AND status = ? AND flagged_at IS NULL", photo.ID, entity.PhotoPending).
+		Updates(updates)
+
+	return flagged, res.Error
+}
+
+func (r *PhotoRepo) GetReviewQueue(ctx context.Context, limit int, offset int) ([]entity.Photo, error) {
+	var photos []entity.Photo
+	res := r.db.WithContext(ctx).
+		Where("status = ? AND flagged_at IS NOT NULL", entity.PhotoPending).
+		Order("flagged_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&photos)
+
+	return photos, res.Error
+}
+
+func (r *PhotoRepo) GetPhotoByID(ctx context.Context, photoID int) (*entity.Photo, error) {
+	var photo entity.Photo
+	res := r.db.WithContext(ctx).Where("id = ?", photoID).First(&photo)
+	return &photo, res.Error
+}
+
+func (r *PhotoRepo) ReviewPhoto(ctx context.Context, photoID int, status entity.PhotoStatus, note string, reviewedAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.Photo{}).
+		Where("id = ? AND status = ?", photoID, entity.PhotoPending).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"moderation_note": note,
+			"reviewed_at":     reviewedAt,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,93 @@
+package reportRepo
+
+import (
+	"context"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"gorm.io/gorm"
+)
+
+type IReportRepo interface {
+	CreateReport(ctx context.Context, report *entity.Report) (*entity.Report, error)
+	GetReportByID(ctx context.Context, id int) (*entity.Report, error)
+
+	// Moderation queue, oldest first
+	GetReportsByStatus(ctx context.Context, status entity.ReportStatus, limit int, offset int) ([]entity.Report, error)
+
+	// Write the review of a report that's still pending, returns false when
+	// another moderator reviewed it first
+	ResolveReport(ctx context.Context, report *entity.Report) (bool, error)
+
+	// Put a resolved report back in the queue, used when its action failed
+	ReopenReport(ctx context.Context, reportID uint) error
+
+	// IDs of users the reporter has reported, hidden from the reporter's deck
+	GetReportedIDs(ctx context.Context, reporterID int) ([]int, error)
+}
+
+type ReportRepo struct {
+	db *gorm.DB
+}
+
+func NewReportRepo(db *gorm.DB) IReportRepo {
+	return &ReportRepo{
+		db: db,
+	}
+}
+
+func (r *ReportRepo) CreateReport(ctx context.Context, report *entity.Report) (*entity.Report, error) {
+	res := r.db.WithContext(ctx).Create(report)
+	return report, res.Error
+}
+
+func (r *ReportRepo) GetReportByID(ctx context.Context, id int) (*entity.Report, error) {
+	var report entity.Report
+	res := r.db.WithContext(ctx).Where("id = ?", id).First(&report)
+	return &report, res.Error
+}
+
+func (r *ReportRepo) GetReportsByStatus(ctx context.Context, status entity.ReportStatus, limit int, offset int) ([]entity.Report, error) {
+	var reports []entity.Report
+	res := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&reports)
+
+	return reports, res.Error
+}
+
+func (r *ReportRepo) ResolveReport(ctx context.Context, report *entity.Report) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.Report{}).
+		Where("id = ? AND status = ?", report.ID, entity.ReportStatusPending).
+		Select("status", "action", "resolution_note", "reviewer_id", "reviewed_at").
+		Updates(report)
+
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *ReportRepo) ReopenReport(ctx context.Context, reportID uint) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.Report{}).
+		Where("id = ?", reportID).
+		Updates(map[string]interface{}{
+			"status":          entity.ReportStatusPending,
+			"action":          nil,
+			"resolution_note": "",
+			"reviewer_id":     nil,
+			"reviewed_at":     nil,
+		}).Error
+}
+
+func (r *ReportRepo) GetReportedIDs(ctx context.Context, reporterID int) ([]int, error) {
+	var ids []int
+	res := r.db.WithContext(ctx).
+		Model(&entity.Report{}).
+		Distinct("reported_id").
+		Where("reporter_id = ?", reporterID).
+		Pluck("reported_id", &ids)
+
+	return ids, res.Error
+}
@@ -0,0 +1,119 @@
+package securityRepo
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+type ISecurityRepo interface {
+	// Add a failure to the sliding window of the key, returns the number of
+	// failures in the last window
+	AddFailure(ctx context.Context, key string, at time.Time, window time.Duration) (int64, error)
+	ClearFailures(ctx context.Context, key string) error
+
+	// Count a lockout of the key, returns the number of lockouts in the window
+	CountLockout(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+
+	// Remaining time the key is locked for, zero when it isn't locked
+	GetLock(ctx context.Context, key string) (time.Duration, error)
+
+	CreateAuditLog(ctx context.Context, log *entity.SecurityAuditLog) error
+}
+
+type SecurityRepo struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewSecurityRepo(db *gorm.DB, redis *redis.Client) ISecurityRepo {
+	return &SecurityRepo{
+		db:  db,
+		rdb: redis,
+	}
+}
+
+func (r *SecurityRepo) AddFailure(_ context.Context, key string, at time.Time, window time.Duration) (int64, error) {
+	key = failuresKey(key)
+	var count *redis.IntCmd
+
+	// Failures are scored by time, the ones older than the window are dropped
+	// before counting
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(key, redis.Z{Score: float64(at.UnixNano()), Member: at.UnixNano()})
+		pipe.ZRemRangeByScore(key, "-inf", "("+strconv.FormatInt(at.Add(-window).UnixNano(), 10))
+		count = pipe.ZCard(key)
+		pipe.Expire(key, window)
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+func (r *SecurityRepo) ClearFailures(_ context.Context, key string) error {
+	return r.rdb.Del(failuresKey(key)).Err()
+}
+
+func (r *SecurityRepo) CountLockout(_ context.Context, key string, window time.Duration) (int64, error) {
+	key = lockoutsKey(key)
+
+	var count *redis.IntCmd
+
+	// Every lockout extends the window, the count only resets after a quiet one
+	_, err := r.rdb.TxPipelined(func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(key)
+		pipe.Expire(key, window)
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+func (r *SecurityRepo) Lock(_ context.Context, key string, ttl time.Duration) error {
+	return r.rdb.Set(lockKey(key), 1, ttl).Err()
+}
+
+func (r *SecurityRepo) GetLock(_ context.Context, key string) (time.Duration, error) {
+	ttl, err := r.rdb.PTTL(lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Negative for a missing key
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (r *SecurityRepo) CreateAuditLog(ctx context.Context, log *entity.SecurityAuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// Helper
+
+func failuresKey(key string) string {
+	return key + ":failures"
+}
+
+func lockoutsKey(key string) string {
+	return key + ":lockouts"
+}
+
+func lockKey(key string) string {
+	return key + ":locked"
+}
@@ -0,0 +1,194 @@
+package tokenRepo
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Increment and start the window in one step, a counter can't be left
+// without an expiry and block the key for good
+var countAttemptScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+type ITokenRepo interface {
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+
+	// Mark the token used and store its successor, returns false without
+	// storing anything when the token was already used or revoked
+	RotateRefreshToken(ctx context.Context, tokenID uint, next *entity.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int) error
+
+	// Access token revocation list in Redis, entries expire with the token
+	RevokeAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+
+	// Access tokens of the user issued at or before revokedAt are rejected
+	RevokeUserAccessTokens(ctx context.Context, userID int, revokedAt time.Time, ttl time.Duration) error
+	GetUserAccessTokensRevokedAt(ctx context.Context, userID int) (*time.Time, error)
+
+	CreateOneTimeToken(ctx context.Context, token *entity.OneTimeToken) error
+
+	// Mark the token used and return it, gorm.ErrRecordNotFound when it
+	// doesn't exist, expired or was already used
+	ConsumeOneTimeToken(ctx context.Context, purpose entity.TokenPurpose, tokenHash string) (*entity.OneTimeToken, error)
+
+	// Mark every unused token of the user for the purpose as used
+	InvalidateOneTimeTokens(ctx context.Context, userID int, purpose entity.TokenPurpose) error
+
+	// Count an attempt in a fixed window starting with the first attempt,
+	// returns the number of attempts in the current window
+	CountAttempt(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+type TokenRepo struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewTokenRepo(db *gorm.DB, redis *redis.Client) ITokenRepo {
+	return &TokenRepo{
+		db:  db,
+		rdb: redis,
+	}
+}
+
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *TokenRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	res := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	return &token, res.Error
+}
+
+func (r *TokenRepo) RotateRefreshToken(ctx context.Context, tokenID uint, next *entity.RefreshToken) (bool, error) {
+	rotated := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional update makes concurrent rotations of the same token
+		// race on the row, only one of them sees a row affected
+		res := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", tokenID).
+			Update("used_at", time.Now())
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		rotated = true
+		return tx.Create(next).Error
+	})
+
+	return rotated, err
+}
+
+func (r *TokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *TokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *TokenRepo) RevokeAccessToken(_ context.Context, tokenID string, ttl time.Duration) error {
+	// Already expired, nothing to revoke
+	if ttl <= 0 {
+		return nil
+	}
+
+	return r.rdb.Set(revokedTokenKey(tokenID), 1, ttl).Err()
+}
+
+func (r *TokenRepo) IsAccessTokenRevoked(_ context.Context, tokenID string) (bool, error) {
+	exists, err := r.rdb.Exists(revokedTokenKey(tokenID)).Result()
+	return exists > 0, err
+}
+
+func (r *TokenRepo) RevokeUserAccessTokens(_ context.Context, userID int, revokedAt time.Time, ttl time.Duration) error {
+	return r.rdb.Set(userTokensRevokedKey(userID), revokedAt.Unix(), ttl).Err()
+}
+
+func (r *TokenRepo) GetUserAccessTokensRevokedAt(_ context.Context, userID int) (*time.Time, error) {
+	unix, err := r.rdb.Get(userTokensRevokedKey(userID)).Int64()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	revokedAt := time.Unix(unix, 0)
+	return &revokedAt, nil
+}
+
+func (r *TokenRepo) CreateOneTimeToken(ctx context.Context, token *entity.OneTimeToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *TokenRepo) ConsumeOneTimeToken(ctx context.Context, purpose entity.TokenPurpose, tokenHash string) (*entity.OneTimeToken, error) {
+	var token entity.OneTimeToken
+
+	// A single conditional update so the same token can't be consumed twice
+	res := r.db.WithContext(ctx).
+		Model(&token).
+		Clauses(clause.Returning{}).
+		Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, time.Now()).
+		Update("used_at", time.Now())
+
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &token, nil
+}
+
+func (r *TokenRepo) InvalidateOneTimeTokens(ctx context.Context, userID int, purpose entity.TokenPurpose) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *TokenRepo) CountAttempt(_ context.Context, key string, window time.Duration) (int64, error) {
+	return countAttemptScript.Run(r.rdb, []string{key}, window.Milliseconds()).Int64()
+}
+
+// Helper
+
+func revokedTokenKey(tokenID string) string {
+	return ":auth:revoked:" + tokenID
+}
+
+func userTokensRevokedKey(userID int) string {
+	return ":user:" + strconv.Itoa(userID) + ":tokens:revoked_at"
+}
@@ -2,47 +2,376 @@ package userRepo
 
 import (
 	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
 
 	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/go-redis/redis"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Every authenticated request reads the user, the cache entry is dropped
+// whenever the user is updated
+const userCacheTTL = 10 * time.Minute
+
+// Bumped when the cached encoding changes so old entries are never read.
+// Entries before v2 held the password hash
+const userCacheVersion = "v2"
+
+// Users are read with their email, username and phone from auth_credentials,
+// they're created and erased with their credential by IAuthRepo
 type IUserRepo interface {
-	CreateUser(ctx context.Context, user *entity.User) (*entity.User, error)
 	GetUserByID(ctx context.Context, id int) (*entity.User, error)
 	GetUserByUnameOrEmail(ctx context.Context, email, uname string) (*entity.User, error)
+	GetUserByPhone(ctx context.Context, phone string) (*entity.User, error)
+
+	// Whether another user has the email, ignoring case
+	IsEmailTaken(ctx context.Context, email string, exceptUserID int) (bool, error)
+	UpdatePremium(ctx context.Context, userID int, isPremium bool) error
+
+	// Only verifies while the user's email is still the given one
+	MarkEmailVerified(ctx context.Context, userID int, email string) error
+
+	// Change the email and mark it unverified
+	UpdateEmail(ctx context.Context, userID int, email string) error
+
+	// Change the account status and record it in the audit log, actorID is nil for system changes.
+	// Reactivating cancels a scheduled deletion
+	UpdateAccountStatus(ctx context.Context, userID int, status entity.AccountStatus, suspendedUntil *time.Time, reason string, actorID *uint) error
+	GetAccountStatusAudits(ctx context.Context, userID int) ([]entity.AccountStatusAudit, error)
+
+	// Mark the account deleted and record it in the audit log, it's erased by EraseUser after eraseAt
+	ScheduleDeletion(ctx context.Context, userID int, eraseAt time.Time, reason string, actorID *uint) error
+
+	// Users whose deletion is due, who aren't erased yet and aren't waiting to
+	// retry a failed erasure. Only the ID and the erasure attempts are loaded
+	GetUsersDueForErasure(ctx context.Context, now time.Time, limit int) ([]entity.User, error)
+
+	// Count a failed erasure and leave the user until retryAt
+	DelayErasure(ctx context.Context, userID int, retryAt time.Time) error
+
+	// Delete the user's data and Redis keys, the user row is kept without any
+	// personal data so audit logs and reports still point to it
+	EraseUser(ctx context.Context, userID int) error
 }
 
 type UserRepo struct {
-	db *gorm.DB
+	db  *gorm.DB
+	rdb *redis.Client
 }
 
-func New(db *gorm.DB) IUserRepo {
+func New(db *gorm.DB, redis *redis.Client) IUserRepo {
 	return &UserRepo{
-		db: db,
+		db:  db,
+		rdb: redis,
 	}
 }
 
-func (r *UserRepo) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
-	result := r.db.WithContext(ctx).Create(&user)
-	return user, result.Error
-}
-
 func (r *UserRepo) GetUserByID(ctx context.Context, id int) (*entity.User, error) {
 	var user entity.User
-	result := r.db.WithContext(ctx).Where("id = ?", id).First(&user)
-	return &user, result.Error
+
+	cached, err := r.rdb.Get(userCacheKey(id)).Bytes()
+	if err == nil && json.Unmarshal(cached, &user) == nil {
+		return &user, nil
+	}
+
+	if err != nil && err != redis.Nil {
+		log.Println("error reading user cache", err)
+	}
+
+	result := r.db.WithContext(ctx).Scopes(withIdentifiers).Where("users.id = ?", id).First(&user)
+	if result.Error != nil {
+		return &user, result.Error
+	}
+
+	if encoded, err := json.Marshal(user); err == nil {
+		r.rdb.Set(userCacheKey(id), encoded, userCacheTTL)
+	}
+
+	return &user, nil
 }
 
 func (r *UserRepo) GetUserByUnameOrEmail(ctx context.Context, email, uname string) (*entity.User, error) {
 	var user entity.User
-	query := r.db.WithContext(ctx)
+	query := r.db.WithContext(ctx).Scopes(withIdentifiers)
 	if email != "" {
-		query = query.Where("email = ?", email)
+		query = query.Where("LOWER(auth_credentials.email) = LOWER(?)", email)
 	}
 	if uname != "" {
-		query = query.Or("username = ?", uname)
+		query = query.Or("auth_credentials.username = ?", uname)
 	}
 	result := query.First(&user)
 	return &user, result.Error
 }
+
+func (r *UserRepo) IsEmailTaken(ctx context.Context, email string, exceptUserID int) (bool, error) {
+	var count int64
+	res := r.db.WithContext(ctx).
+		Model(&entity.AuthCredential{}).
+		Where("LOWER(email) = LOWER(?) AND user_id <> ?", email, exceptUserID).
+		Count(&count)
+
+	return count > 0, res.Error
+}
+
+func (r *UserRepo) GetUserByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	var user entity.User
+	result := r.db.WithContext(ctx).Scopes(withIdentifiers).Where("auth_credentials.phone = ?", phone).First(&user)
+	return &user, result.Error
+}
+
+func (r *UserRepo) UpdatePremium(ctx context.Context, userID int, isPremium bool) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Update("is_premium", isPremium)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	r.invalidateUserCache(userID)
+
+	return nil
+}
+
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Where("EXISTS (?)", r.db.Model(&entity.AuthCredential{}).Where("user_id = users.id AND LOWER(email) = LOWER(?)", email)).
+		Update("email_verified_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	r.invalidateUserCache(userID)
+
+	return nil
+}
+
+func (r *UserRepo) UpdateEmail(ctx context.Context, userID int, email string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&entity.AuthCredential{}).
+			Where("user_id = ?", userID).
+			Update("email", email)
+
+		// Taken by a concurrent sign-up or change, returned as gorm.ErrDuplicatedKey
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Model(&entity.User{}).
+			Where("id = ?", userID).
+			Update("email_verified_at", nil).Error
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.invalidateUserCache(userID)
+
+	return nil
+}
+
+func (r *UserRepo) UpdateAccountStatus(ctx context.Context, userID int, status entity.AccountStatus, suspendedUntil *time.Time, reason string, actorID *uint) error {
+	fields := map[string]interface{}{
+		"status":          status,
+		"suspended_until": suspendedUntil,
+	}
+
+	if status == entity.AccountActive {
+		fields["deletion_scheduled_at"] = nil
+	}
+
+	return r.changeAccountStatus(ctx, userID, fields, reason, actorID)
+}
+
+func (r *UserRepo) ScheduleDeletion(ctx context.Context, userID int, eraseAt time.Time, reason string, actorID *uint) error {
+	return r.changeAccountStatus(ctx, userID, map[string]interface{}{
+		"status":                entity.AccountDeleted,
+		"suspended_until":       nil,
+		"deletion_scheduled_at": eraseAt,
+		"erasure_attempts":      0,
+		"erasure_retry_at":      nil,
+	}, reason, actorID)
+}
+
+func (r *UserRepo) GetAccountStatusAudits(ctx context.Context, userID int) ([]entity.AccountStatusAudit, error) {
+	var audits []entity.AccountStatusAudit
+	result := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&audits)
+
+	return audits, result.Error
+}
+
+func (r *UserRepo) GetUsersDueForErasure(ctx context.Context, now time.Time, limit int) ([]entity.User, error) {
+	var users []entity.User
+	res := r.db.WithContext(ctx).
+		Select("id", "erasure_attempts").
+		Where("deletion_scheduled_at <= ? AND erased_at IS NULL", now).
+		Where("erasure_retry_at IS NULL OR erasure_retry_at <= ?", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users)
+
+	return users, res.Error
+}
+
+func (r *UserRepo) DelayErasure(ctx context.Context, userID int, retryAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"erasure_attempts": gorm.Expr("erasure_attempts + 1"),
+			"erasure_retry_at": retryAt,
+		}).Error
+}
+
+func (r *UserRepo) EraseUser(ctx context.Context, userID int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? OR to_id = ?", userID, userID).Delete(&entity.SwipeTransaction{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ? OR blocked_id = ?", userID, userID).Delete(&entity.Block{}).Error; err != nil {
+			return err
+		}
+
+		// Reports and the status audit log are kept for moderation
+		owned := []interface{}{
+			&entity.Device{},
+			&entity.NotificationPreference{},
+			&entity.RefreshToken{},
+			&entity.OneTimeToken{},
+			&entity.SecurityAuditLog{},
+			&entity.TOTPCredential{},
+			&entity.RecoveryCode{},
+			&entity.Identity{},
+			&entity.AuthCredential{},
+			&entity.DataExport{},
+			&entity.Photo{},
+		}
+
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&entity.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"name":                  "Deleted user",
+				"email_verified_at":     nil,
+				"phone_verified_at":     nil,
+				"is_premium":            false,
+				"deletion_scheduled_at": nil,
+				"erasure_retry_at":      nil,
+				"erased_at":             time.Now(),
+			}).Error
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return r.deleteUserKeys(userID)
+}
+
+// Helper
+
+func (r *UserRepo) changeAccountStatus(ctx context.Context, userID int, fields map[string]interface{}, reason string, actorID *uint) error {
+	defer r.invalidateUserCache(userID)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user entity.User
+		res := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", userID).
+			First(&user)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		res = tx.Model(&entity.User{}).
+			Where("id = ?", userID).
+			Updates(fields)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		suspendedUntil, _ := fields["suspended_until"].(*time.Time)
+
+		return tx.Create(&entity.AccountStatusAudit{
+			UserID:         uint(userID),
+			FromStatus:     user.AccountStatusAt(time.Now()),
+			ToStatus:       fields["status"].(entity.AccountStatus),
+			SuspendedUntil: suspendedUntil,
+			Reason:         reason,
+			ActorID:        actorID,
+		}).Error
+	})
+}
+
+// The login identifiers are stored with the credential
+func withIdentifiers(db *gorm.DB) *gorm.DB {
+	return db.
+		Select("users.*, auth_credentials.email, auth_credentials.username, auth_credentials.phone").
+		Joins("LEFT JOIN auth_credentials ON auth_credentials.user_id = users.id")
+}
+
+// Every key under :user:<id>:, the user record cache included
+func (r *UserRepo) deleteUserKeys(userID int) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := r.rdb.Scan(cursor, ":user:"+strconv.Itoa(userID)+":*", 100).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := r.rdb.Del(keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (r *UserRepo) invalidateUserCache(userID int) {
+	if err := r.rdb.Del(userCacheKey(userID)).Err(); err != nil {
+		log.Println("error invalidating user cache", err)
+	}
+}
+
+func userCacheKey(userID int) string {
+	return ":user:" + strconv.Itoa(userID) + ":record:" + userCacheVersion
+}
@@ -0,0 +1,76 @@
+package routesV1Account
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+func ChangeEmailHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.ChangeEmailRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.ChangeEmail(c.Request().Context(), reqBody.Password, reqBody.Email)
+
+	if errors.Is(err, authUseCase.ErrInvalidPassword) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "password", Detail: "invalid password"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "password", Detail: "too many wrong passwords, try again later"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrEmailTaken) {
+		return http_util.Encode(c, http.StatusConflict, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "email", Detail: "email already in use"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to change email"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Email changed, check the new email to verify it",
+	})
+}
+
+func DeleteAccountHandler(c echo.Context, accountCase accountUseCase.IAccountUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	eraseAt, err := accountCase.DeleteAccount(c.Request().Context(), int(user.ID))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to delete account"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.AccountDeletionResponse]{
+		Message: "Account deleted, sign in before it's erased to restore it",
+		Data:    entity.AccountDeletionResponse{EraseAt: eraseAt},
+	})
+}
@@ -0,0 +1,124 @@
+package routesV1Account
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	exportUseCase "github.com/ghaniswara/dating-app/internal/usecase/export"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+func RequestExportHandler(c echo.Context, exportCase exportUseCase.IExportUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	export, err := exportCase.RequestExport(c.Request().Context(), int(user.ID))
+
+	if errors.Is(err, exportUseCase.ErrExportRequested) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[entity.DataExportResponse]{
+			HTTPResponse: http_util.HTTPResponse[entity.DataExportResponse]{
+				Message: "Export already requested",
+				Data:    newDataExportResponse(*export, exportCase),
+			},
+			Errors: []http_util.ErrorResponse{{Property: "export", Detail: "an export was already requested in the last 24 hours"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to request export"})
+	}
+
+	return http_util.Encode(c, http.StatusAccepted, http_util.HTTPResponse[entity.DataExportResponse]{
+		Message: "Export requested, poll it until it's ready to download",
+		Data:    newDataExportResponse(*export, exportCase),
+	})
+}
+
+func GetExportHandler(c echo.Context, exportCase exportUseCase.IExportUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	exportID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	export, err := exportCase.GetExport(c.Request().Context(), int(user.ID), exportID)
+
+	if errors.Is(err, exportUseCase.ErrExportNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "export not found"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get export"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.DataExportResponse]{
+		Message: "Export",
+		Data:    newDataExportResponse(*export, exportCase),
+	})
+}
+
+// Reached through the signed link, without a token so it opens in a browser
+func DownloadExportHandler(c echo.Context, exportCase exportUseCase.IExportUseCase) error {
+	exportID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	archive, err := exportCase.GetArchive(c.Request().Context(), exportID, c.QueryParam("expires"), c.QueryParam("signature"))
+
+	if errors.Is(err, exportUseCase.ErrInvalidDownloadLink) {
+		return http_util.Encode(c, http.StatusForbidden, map[string]string{"error": "invalid or expired download link"})
+	}
+
+	if errors.Is(err, exportUseCase.ErrExportNotReady) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "export not ready or expired"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to download export"})
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"data-export-%d.zip\"", exportID))
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
+
+func newDataExportResponse(export entity.DataExport, exportCase exportUseCase.IExportUseCase) entity.DataExportResponse {
+	response := entity.DataExportResponse{
+		ID:               int(export.ID),
+		Status:           export.Status.String(),
+		CreatedAt:        export.CreatedAt,
+		CompletedAt:      export.CompletedAt,
+		ArchiveExpiresAt: export.ExpiresAt,
+	}
+
+	if export.Status == entity.DataExportReady {
+		downloadExpiresAt := time.Now().Add(exportUseCase.DownloadLinkTTL)
+		if export.ExpiresAt != nil && export.ExpiresAt.Before(downloadExpiresAt) {
+			downloadExpiresAt = *export.ExpiresAt
+		}
+
+		response.DownloadURL = exportCase.DownloadURL(int(export.ID), downloadExpiresAt)
+		response.DownloadExpiresAt = &downloadExpiresAt
+	}
+
+	return response
+}
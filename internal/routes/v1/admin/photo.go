@@ -0,0 +1,103 @@
+package routesV1Admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+func GetPhotoReviewQueueHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	limit, offset := getPagination(c)
+
+	photos, err := photoCase.GetReviewQueue(c.Request().Context(), limit, offset)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get photos"})
+	}
+
+	response := entity.PhotoReviewListResponse{Photos: []entity.PhotoReviewResponse{}}
+
+	for _, photo := range photos {
+		response.Photos = append(response.Photos, entity.NewPhotoReviewResponse(photo))
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.PhotoReviewListResponse]{
+		Message: "Photos awaiting review",
+		Data:    response,
+	})
+}
+
+func ApprovePhotoHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	reqBody, err := http_util.Decode[entity.ApprovePhotoRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	photoID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	photo, err := photoCase.ApprovePhoto(c.Request().Context(), photoID, reqBody)
+
+	if err != nil {
+		return encodePhotoReviewError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.PhotoReviewResponse]{
+		Message: "Photo approved",
+		Data:    entity.NewPhotoReviewResponse(*photo),
+	})
+}
+
+func RejectPhotoHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	reqBody, err := http_util.Decode[entity.RejectPhotoRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	photoID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	photo, err := photoCase.RejectPhoto(c.Request().Context(), photoID, reqBody)
+
+	if err != nil {
+		return encodePhotoReviewError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.PhotoReviewResponse]{
+		Message: "Photo rejected",
+		Data:    entity.NewPhotoReviewResponse(*photo),
+	})
+}
+
+func encodePhotoReviewError(c echo.Context, err error) error {
+	if errors.Is(err, photoUseCase.ErrPhotoNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "photo not found"})
+	}
+
+	if errors.Is(err, photoUseCase.ErrPhotoNotPending) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "photo has already been reviewed"})
+	}
+
+	return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to review photo"})
+}
@@ -0,0 +1,173 @@
+package routesV1Admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	reportUseCase "github.com/ghaniswara/dating-app/internal/usecase/report"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func GetReportsHandler(c echo.Context, reportCase reportUseCase.IReportUseCase) error {
+	status := entity.ReportStatusPending
+
+	if c.QueryParam("status") != "" {
+		parsed, ok := entity.ParseReportStatus(c.QueryParam("status"))
+		if !ok {
+			return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid status"})
+		}
+		status = parsed
+	}
+
+	limit, offset := getPagination(c)
+
+	reports, err := reportCase.GetReports(c.Request().Context(), status, limit, offset)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get reports"})
+	}
+
+	response := entity.ReportListResponse{Reports: []entity.ReportResponse{}}
+
+	for _, report := range reports {
+		response.Reports = append(response.Reports, entity.NewReportResponse(report))
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.ReportListResponse]{
+		Message: "Reports",
+		Data:    response,
+	})
+}
+
+func GetReportHandler(c echo.Context, reportCase reportUseCase.IReportUseCase) error {
+	reportID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	report, err := reportCase.GetReport(c.Request().Context(), reportID)
+
+	if err != nil {
+		return encodeReportError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.ReportResponse]{
+		Message: "Report",
+		Data:    entity.NewReportResponse(*report),
+	})
+}
+
+func DismissReportHandler(c echo.Context, reportCase reportUseCase.IReportUseCase) error {
+	reqBody, err := http_util.Decode[entity.DismissReportRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	reportID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	reviewer, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	report, err := reportCase.DismissReport(c.Request().Context(), reportID, reqBody, reviewer)
+
+	if err != nil {
+		return encodeReportError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.ReportResponse]{
+		Message: "Report dismissed",
+		Data:    entity.NewReportResponse(*report),
+	})
+}
+
+func ActionReportHandler(c echo.Context, reportCase reportUseCase.IReportUseCase) error {
+	reqBody, err := http_util.Decode[entity.ResolveReportRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	reportID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	reviewer, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	report, err := reportCase.ActionReport(c.Request().Context(), reportID, reqBody, reviewer)
+
+	if err != nil {
+		return encodeReportError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.ReportResponse]{
+		Message: "Report actioned",
+		Data:    entity.NewReportResponse(*report),
+	})
+}
+
+func encodeReportError(c echo.Context, err error) error {
+	if errors.Is(err, reportUseCase.ErrReportNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "report not found"})
+	}
+
+	if errors.Is(err, reportUseCase.ErrReportNotPending) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "report has already been reviewed"})
+	}
+
+	if errors.Is(err, accountUseCase.ErrInsufficientRole) {
+		return http_util.Encode(c, http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to process report"})
+}
+
+func getPagination(c echo.Context) (limit int, offset int) {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	offset, err = strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
@@ -0,0 +1,172 @@
+package routesV1Admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	adminUseCase "github.com/ghaniswara/dating-app/internal/usecase/admin"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+// Looks the user up by email or username query parameters
+func FindUserHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	user, err := adminCase.FindUser(c.Request().Context(), c.QueryParam("email"), c.QueryParam("username"))
+
+	if err != nil {
+		return encodeUserError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.AdminUserResponse]{
+		Message: "User",
+		Data:    entity.NewAdminUserResponse(*user),
+	})
+}
+
+func GetUserHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	user, err := adminCase.GetUser(c.Request().Context(), userID)
+
+	if err != nil {
+		return encodeUserError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.AdminUserResponse]{
+		Message: "User",
+		Data:    entity.NewAdminUserResponse(*user),
+	})
+}
+
+func SetPremiumHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	reqBody, err := http_util.Decode[entity.SetPremiumRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	user, err := adminCase.SetPremium(c.Request().Context(), userID, reqBody.IsPremium)
+
+	if err != nil {
+		return encodeUserError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.AdminUserResponse]{
+		Message: "Premium updated",
+		Data:    entity.NewAdminUserResponse(*user),
+	})
+}
+
+func SuspendUserHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	reqBody, err := http_util.Decode[entity.SuspendUserRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	actor, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	user, err := adminCase.SuspendUser(c.Request().Context(), userID, reqBody, actor)
+
+	if err != nil {
+		return encodeUserError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.AdminUserResponse]{
+		Message: "User suspended",
+		Data:    entity.NewAdminUserResponse(*user),
+	})
+}
+
+func GetSwipeHistoryHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	limit, offset := getPagination(c)
+
+	swipes, err := adminCase.GetSwipeHistory(c.Request().Context(), userID, limit, offset)
+
+	if err != nil {
+		return encodeUserError(c, err)
+	}
+
+	response := entity.SwipeHistoryResponse{Swipes: []entity.SwipeResponse{}}
+
+	for _, swipe := range swipes {
+		response.Swipes = append(response.Swipes, entity.SwipeResponse{
+			ID:        int(swipe.ID),
+			ToID:      int(swipe.ToID),
+			Action:    swipe.Action.String(),
+			IsMatched: swipe.IsMatched,
+			Time:      swipe.Time,
+		})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SwipeHistoryResponse]{
+		Message: "Swipe history",
+		Data:    response,
+	})
+}
+
+func ResetLikeQuotaHandler(c echo.Context, adminCase adminUseCase.IAdminUseCase) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := adminCase.ResetLikeQuota(c.Request().Context(), userID); err != nil {
+		return encodeUserError(c, err)
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Like quota reset",
+	})
+}
+
+func encodeUserError(c echo.Context, err error) error {
+	if errors.Is(err, adminUseCase.ErrUserNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "user not found"})
+	}
+
+	if errors.Is(err, accountUseCase.ErrInsufficientRole) {
+		return http_util.Encode(c, http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to process user"})
+}
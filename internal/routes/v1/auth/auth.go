@@ -1,10 +1,15 @@
 package routesV1Auth
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
 	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/clientip"
 	"github.com/ghaniswara/dating-app/pkg/http_util"
 
 	"github.com/labstack/echo"
@@ -59,7 +64,37 @@ func SignInHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
 		})
 	}
 
-	jwtToken, err := authCase.SignIn(c.Request().Context(), reqBody.Email, reqBody.Username, reqBody.Password)
+	tokens, err := authCase.SignIn(c.Request().Context(), reqBody.Email, reqBody.Username, reqBody.Password, clientip.FromContext(c.Request().Context()))
+
+	if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+		return http_util.Encode(c, http.StatusForbidden, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "account", Detail: err.Error()}},
+		})
+	}
+
+	var signInErr *authUseCase.SignInError
+
+	if errors.As(err, &signInErr) && errors.Is(err, authUseCase.ErrSignInLocked) {
+		retryAfter := int(math.Ceil(signInErr.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[entity.SignInFailureResponse]{
+			HTTPResponse: http_util.HTTPResponse[entity.SignInFailureResponse]{
+				Message: "too many failed sign-ins, try again later",
+				Data:    entity.SignInFailureResponse{CaptchaRequired: signInErr.CaptchaRequired, RetryAfter: retryAfter},
+			},
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "too many failed sign-ins"}},
+		})
+	}
+
+	if errors.As(err, &signInErr) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInFailureResponse]{
+			HTTPResponse: http_util.HTTPResponse[entity.SignInFailureResponse]{
+				Data: entity.SignInFailureResponse{CaptchaRequired: signInErr.CaptchaRequired},
+			},
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "invalid credentials"}},
+		})
+	}
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
@@ -67,8 +102,250 @@ func SignInHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
 		})
 	}
 
+	message := "Sign-in successful"
+	if tokens.MFARequired {
+		message = "Two-factor authentication required"
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SignInResponse]{
+		Message: message,
+		Data:    *tokens,
+	})
+}
+
+func RefreshHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.RefreshTokenRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	tokens, err := authCase.Refresh(c.Request().Context(), reqBody.RefreshToken)
+
+	if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+		return http_util.Encode(c, http.StatusForbidden, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "account", Detail: err.Error()}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidRefreshToken) || errors.Is(err, authUseCase.ErrRefreshTokenReused) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "refresh_token", Detail: "invalid refresh token"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to refresh token"})
+	}
+
 	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SignInResponse]{
-		Message: "Sign-in successful",
-		Data:    entity.SignInResponse{Token: jwtToken},
+		Message: "Token refreshed",
+		Data:    *tokens,
+	})
+}
+
+func LogoutHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.LogoutRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := authCase.Logout(c.Request().Context(), reqBody.RefreshToken); err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to log out"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Logged out",
+	})
+}
+
+func LogoutAllHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	if err := authCase.LogoutAll(c.Request().Context()); err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to log out"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Logged out from all devices",
+	})
+}
+
+func VerifyEmailHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.VerifyEmailRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.VerifyEmail(c.Request().Context(), reqBody.Token)
+
+	if errors.Is(err, authUseCase.ErrInvalidVerificationToken) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "token", Detail: "invalid or expired token"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to verify email"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Email verified",
+	})
+}
+
+func ResendVerificationHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	err = authCase.ResendVerificationEmail(c.Request().Context(), int(user.ID))
+
+	if errors.Is(err, authUseCase.ErrEmailAlreadyVerified) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "email already verified"})
+	}
+
+	if errors.Is(err, authUseCase.ErrNoEmail) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "account has no email"})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "too many verification emails, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to send verification email"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Verification email sent",
+	})
+}
+
+func ForgotPasswordHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.ForgotPasswordRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.ForgotPassword(c.Request().Context(), reqBody.Email, clientip.FromContext(c.Request().Context()))
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "too many reset requests, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to request password reset"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "If the email belongs to an account, a reset link has been sent",
+	})
+}
+
+func ResetPasswordHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.ResetPasswordRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.ResetPassword(c.Request().Context(), reqBody.Token, reqBody.Password, clientip.FromContext(c.Request().Context()))
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "too many reset attempts, try again later"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidResetToken) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "token", Detail: "invalid or expired token"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Password reset, sign in with the new password",
+	})
+}
+
+func ChangePasswordHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.ChangePasswordRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.ChangePassword(c.Request().Context(), reqBody.CurrentPassword, reqBody.NewPassword)
+
+	if errors.Is(err, authUseCase.ErrInvalidPassword) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "current_password", Detail: "invalid password"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "current_password", Detail: "too many wrong passwords, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to change password"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Password changed, sign in with the new password",
 	})
 }
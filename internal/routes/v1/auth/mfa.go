@@ -0,0 +1,170 @@
+package routesV1Auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+func EnrollTOTPHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	enrollment, err := authCase.EnrollTOTP(c.Request().Context())
+
+	if errors.Is(err, authUseCase.ErrMFAAlreadyEnabled) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "two-factor authentication already enabled"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to enroll"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.TOTPEnrollmentResponse]{
+		Message: "Add the secret to an authenticator app and confirm with a code",
+		Data:    *enrollment,
+	})
+}
+
+func ConfirmTOTPHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.MFACodeRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	codes, err := authCase.ConfirmTOTP(c.Request().Context(), reqBody.Code)
+
+	if errors.Is(err, authUseCase.ErrMFAAlreadyEnabled) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "two-factor authentication already enabled"})
+	}
+
+	if errors.Is(err, authUseCase.ErrMFANotEnrolled) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "two-factor authentication not enrolled"})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidMFACode) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "invalid code"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "too many attempts, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to enable two-factor authentication"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.RecoveryCodesResponse]{
+		Message: "Two-factor authentication enabled, store the recovery codes somewhere safe",
+		Data:    *codes,
+	})
+}
+
+func DisableTOTPHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.MFACodeRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.DisableTOTP(c.Request().Context(), reqBody.Code)
+
+	if errors.Is(err, authUseCase.ErrMFANotEnrolled) {
+		return http_util.Encode(c, http.StatusConflict, map[string]string{"error": "two-factor authentication not enabled"})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidMFACode) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "invalid code"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "too many attempts, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to disable two-factor authentication"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+func MFAChallengeHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.MFAChallengeRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	tokens, err := authCase.CompleteMFAChallenge(c.Request().Context(), reqBody.ChallengeToken, reqBody.Code)
+
+	if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+		return http_util.Encode(c, http.StatusForbidden, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "account", Detail: err.Error()}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidChallenge) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "challenge_token", Detail: "invalid or expired challenge, sign in again"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidMFACode) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "invalid code"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "too many attempts, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to sign in"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SignInResponse]{
+		Message: "Sign-in successful",
+		Data:    *tokens,
+	})
+}
@@ -0,0 +1,95 @@
+package routesV1Auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+func OIDCStartHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	url, err := authCase.StartOIDC(c.Request().Context(), c.Param("provider"), c.QueryParam("login_hint"))
+
+	if errors.Is(err, authUseCase.ErrUnknownProvider) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "unknown provider"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadGateway, map[string]string{"error": "failed to reach the provider"})
+	}
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+// Providers call back with a GET, or a POST with response_mode=form_post
+func OIDCCallbackHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	if providerErr := c.FormValue("error"); providerErr != "" {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "provider", Detail: providerErr}},
+		})
+	}
+
+	state := c.FormValue("state")
+	code := c.FormValue("code")
+
+	if state == "" || code == "" {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	tokens, err := authCase.CompleteOIDC(c.Request().Context(), c.Param("provider"), state, code)
+
+	if errors.Is(err, authUseCase.ErrUnknownProvider) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "unknown provider"})
+	}
+
+	if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+		return http_util.Encode(c, http.StatusForbidden, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "account", Detail: err.Error()}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidOIDCState) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "state", Detail: "invalid or expired state, sign in again"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrOIDCEmailRequired) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "email", Detail: "the provider didn't share an email"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrOIDCEmailInUse) {
+		return http_util.Encode(c, http.StatusConflict, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "email", Detail: "email belongs to another account, sign in with it first"}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrOIDCFailed) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "provider", Detail: "sign-in with the provider failed"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to sign in"})
+	}
+
+	message := "Sign-in successful"
+	if tokens.MFARequired {
+		message = "Two-factor authentication required"
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SignInResponse]{
+		Message: message,
+		Data:    *tokens,
+	})
+}
@@ -0,0 +1,90 @@
+package routesV1Auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	"github.com/ghaniswara/dating-app/pkg/clientip"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+func SendPhoneOTPHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.PhoneOTPRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	err = authCase.SendPhoneOTP(c.Request().Context(), reqBody.Phone, clientip.FromContext(c.Request().Context()))
+
+	if errors.Is(err, authUseCase.ErrTooManyRequests) {
+		return http_util.Encode(c, http.StatusTooManyRequests, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "request", Detail: "too many codes requested, try again later"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to send code"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Code sent",
+	})
+}
+
+func PhoneSignInHandler(c echo.Context, authCase authUseCase.IAuthUseCase) error {
+	reqBody, err := http_util.Decode[entity.PhoneSignInRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	tokens, err := authCase.SignInWithPhone(c.Request().Context(), reqBody.Phone, reqBody.Code, reqBody.Name)
+
+	if errors.Is(err, accountUseCase.ErrAccountSuspended) || errors.Is(err, accountUseCase.ErrAccountBanned) {
+		return http_util.Encode(c, http.StatusForbidden, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "account", Detail: err.Error()}},
+		})
+	}
+
+	if errors.Is(err, authUseCase.ErrInvalidPhoneOTP) {
+		return http_util.Encode(c, http.StatusUnauthorized, http_util.HTTPErrorResponse[entity.SignInResponse]{
+			Errors: []http_util.ErrorResponse{{Property: "code", Detail: "invalid or expired code"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to sign in"})
+	}
+
+	message := "Sign-in successful"
+	if tokens.MFARequired {
+		message = "Two-factor authentication required"
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.SignInResponse]{
+		Message: message,
+		Data:    *tokens,
+	})
+}
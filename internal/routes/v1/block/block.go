@@ -0,0 +1,95 @@
+package routesV1Block
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	blockUseCase "github.com/ghaniswara/dating-app/internal/usecase/block"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+func BlockHandler(c echo.Context, blockCase blockUseCase.IBlockUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	blockedID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	err = blockCase.BlockUser(c.Request().Context(), int(user.ID), blockedID)
+
+	if errors.Is(err, blockUseCase.ErrBlockSelf) {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "cannot block yourself"})
+	}
+
+	if errors.Is(err, blockUseCase.ErrUserNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "user not found"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to block user"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "User blocked",
+	})
+}
+
+func UnblockHandler(c echo.Context, blockCase blockUseCase.IBlockUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	blockedID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := blockCase.UnblockUser(c.Request().Context(), int(user.ID), blockedID); err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to unblock user"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "User unblocked",
+	})
+}
+
+func GetBlocksHandler(c echo.Context, blockCase blockUseCase.IBlockUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	blocks, err := blockCase.GetBlockedUsers(c.Request().Context(), int(user.ID))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get blocked users"})
+	}
+
+	response := entity.BlockListResponse{Blocks: []entity.BlockResponse{}}
+
+	for _, block := range blocks {
+		response.Blocks = append(response.Blocks, entity.BlockResponse{
+			UserID:    int(block.BlockedID),
+			CreatedAt: block.CreatedAt,
+		})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.BlockListResponse]{
+		Message: "Blocked users",
+		Data:    response,
+	})
+}
@@ -0,0 +1,93 @@
+package routesV1Event
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	eventUseCase "github.com/ghaniswara/dating-app/internal/usecase/event"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+// Proxies tend to drop idle connections, send a comment line at least this often
+const heartbeatInterval = 15 * time.Second
+
+var eventIDRegex = regexp.MustCompile(`^\d+-\d+$`)
+
+func StreamHandler(c echo.Context, eventCase eventUseCase.IEventUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	// Browsers send Last-Event-ID on reconnect, the query param allows resuming from a fresh EventSource
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+
+	if lastEventID != "" && !eventIDRegex.MatchString(lastEventID) {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid last event id"})
+	}
+
+	ctx := c.Request().Context()
+	userID := int(user.ID)
+
+	cursor, err := eventCase.GetCursor(ctx, userID, lastEventID)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to open event stream"})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", 3000); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	closed := res.CloseNotify()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-closed:
+			return nil
+		default:
+		}
+
+		events, err := eventCase.WaitForEvents(ctx, userID, cursor, heartbeatInterval)
+
+		if err != nil {
+			// Headers are already sent, the client will reconnect with its Last-Event-ID
+			log.Println("error reading user events", err)
+			return nil
+		}
+
+		if len(events) == 0 {
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+
+		for _, event := range events {
+			if _, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data); err != nil {
+				return nil
+			}
+			cursor = event.ID
+		}
+
+		res.Flush()
+	}
+}
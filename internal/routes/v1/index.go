@@ -1,12 +1,28 @@
 package routesV1
 
 import (
+	"github.com/ghaniswara/dating-app/internal/entity"
 	"github.com/ghaniswara/dating-app/internal/middleware"
 	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	routesV1Account "github.com/ghaniswara/dating-app/internal/routes/v1/account"
+	routesV1Admin "github.com/ghaniswara/dating-app/internal/routes/v1/admin"
 	routesV1Auth "github.com/ghaniswara/dating-app/internal/routes/v1/auth"
+	routesV1Block "github.com/ghaniswara/dating-app/internal/routes/v1/block"
+	routesV1Event "github.com/ghaniswara/dating-app/internal/routes/v1/event"
 	routesV1Match "github.com/ghaniswara/dating-app/internal/routes/v1/match"
+	routesV1Notification "github.com/ghaniswara/dating-app/internal/routes/v1/notification"
+	routesV1Profile "github.com/ghaniswara/dating-app/internal/routes/v1/profile"
+	routesV1Report "github.com/ghaniswara/dating-app/internal/routes/v1/report"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	adminUseCase "github.com/ghaniswara/dating-app/internal/usecase/admin"
 	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	blockUseCase "github.com/ghaniswara/dating-app/internal/usecase/block"
+	eventUseCase "github.com/ghaniswara/dating-app/internal/usecase/event"
+	exportUseCase "github.com/ghaniswara/dating-app/internal/usecase/export"
 	matchUseCase "github.com/ghaniswara/dating-app/internal/usecase/match"
+	notificationUseCase "github.com/ghaniswara/dating-app/internal/usecase/notification"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	reportUseCase "github.com/ghaniswara/dating-app/internal/usecase/report"
 	"github.com/labstack/echo"
 )
 
@@ -14,27 +30,206 @@ func InitV1Routes(
 	e *echo.Echo,
 	authCase authUseCase.IAuthUseCase,
 	matchCase matchUseCase.IMatchUseCase,
+	eventCase eventUseCase.IEventUseCase,
+	notificationCase notificationUseCase.INotificationUseCase,
+	blockCase blockUseCase.IBlockUseCase,
+	reportCase reportUseCase.IReportUseCase,
+	adminCase adminUseCase.IAdminUseCase,
+	accountCase accountUseCase.IAccountUseCase,
+	exportCase exportUseCase.IExportUseCase,
+	photoCase photoUseCase.IPhotoUseCase,
 	userRepo userRepo.IUserRepo,
+	rateLimiter *middleware.RateLimiter,
 ) {
-	v1 := e.Group("/v1")
+	// The default limit runs before authentication so it counts per IP, route
+	// limits after JWTMiddleware count per user
+	v1 := e.Group("/v1", rateLimiter.Limit(middleware.PolicyDefault))
+	jwtMiddleware := middleware.JWTMiddleware(authCase)
 
 	authGroup := v1.Group("/auth")
 	authGroup.POST("/sign-up", func(c echo.Context) error {
 		return routesV1Auth.SignUpHandler(c, authCase)
-	})
+	}, rateLimiter.Limit(middleware.PolicySignUp))
 	authGroup.POST("/sign-in", func(c echo.Context) error {
 		return routesV1Auth.SignInHandler(c, authCase)
+	}, rateLimiter.Limit(middleware.PolicySignIn))
+	authGroup.GET("/oidc/:provider/start", func(c echo.Context) error {
+		return routesV1Auth.OIDCStartHandler(c, authCase)
+	}, rateLimiter.Limit(middleware.PolicySignIn))
+	oidcCallback := func(c echo.Context) error {
+		return routesV1Auth.OIDCCallbackHandler(c, authCase)
+	}
+	authGroup.GET("/oidc/:provider/callback", oidcCallback)
+	authGroup.POST("/oidc/:provider/callback", oidcCallback)
+	authGroup.POST("/phone/otp", func(c echo.Context) error {
+		return routesV1Auth.SendPhoneOTPHandler(c, authCase)
+	}, rateLimiter.Limit(middleware.PolicySignIn))
+	authGroup.POST("/phone/sign-in", func(c echo.Context) error {
+		return routesV1Auth.PhoneSignInHandler(c, authCase)
+	}, rateLimiter.Limit(middleware.PolicySignIn))
+	authGroup.POST("/mfa/challenge", func(c echo.Context) error {
+		return routesV1Auth.MFAChallengeHandler(c, authCase)
+	}, rateLimiter.Limit(middleware.PolicySignIn))
+	authGroup.POST("/mfa/totp", func(c echo.Context) error {
+		return routesV1Auth.EnrollTOTPHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/mfa/totp/confirm", func(c echo.Context) error {
+		return routesV1Auth.ConfirmTOTPHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/mfa/totp/disable", func(c echo.Context) error {
+		return routesV1Auth.DisableTOTPHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/refresh", func(c echo.Context) error {
+		return routesV1Auth.RefreshHandler(c, authCase)
+	})
+	authGroup.POST("/verify-email", func(c echo.Context) error {
+		return routesV1Auth.VerifyEmailHandler(c, authCase)
+	})
+	authGroup.POST("/verify-email/resend", func(c echo.Context) error {
+		return routesV1Auth.ResendVerificationHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/password/forgot", func(c echo.Context) error {
+		return routesV1Auth.ForgotPasswordHandler(c, authCase)
 	})
+	authGroup.POST("/password/reset", func(c echo.Context) error {
+		return routesV1Auth.ResetPasswordHandler(c, authCase)
+	})
+	authGroup.POST("/password/change", func(c echo.Context) error {
+		return routesV1Auth.ChangePasswordHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/logout", func(c echo.Context) error {
+		return routesV1Auth.LogoutHandler(c, authCase)
+	}, jwtMiddleware)
+	authGroup.POST("/logout-all", func(c echo.Context) error {
+		return routesV1Auth.LogoutAllHandler(c, authCase)
+	}, jwtMiddleware)
 
-	matchGroup := v1.Group("/match", middleware.JWTMiddleware())
-	matchGroup.GET("/profile", func(c echo.Context) error {
-		return routesV1Match.GetProfileHandler(c, matchCase, authCase)
+	accountGroup := v1.Group("/account", jwtMiddleware)
+	accountGroup.PUT("/email", func(c echo.Context) error {
+		return routesV1Account.ChangeEmailHandler(c, authCase)
 	})
-	matchGroup.POST("/profile/:id/like", func(c echo.Context) error {
-		return routesV1Match.LikeHandler(c, matchCase, authCase)
+	accountGroup.DELETE("", func(c echo.Context) error {
+		return routesV1Account.DeleteAccountHandler(c, accountCase)
+	})
+	accountGroup.POST("/export", func(c echo.Context) error {
+		return routesV1Account.RequestExportHandler(c, exportCase)
+	})
+	accountGroup.GET("/export/:id", func(c echo.Context) error {
+		return routesV1Account.GetExportHandler(c, exportCase)
+	})
+
+	// Signed link, it works without a token
+	v1.GET("/account/export/:id/download", func(c echo.Context) error {
+		return routesV1Account.DownloadExportHandler(c, exportCase)
+	})
+
+	profileGroup := v1.Group("/profile", jwtMiddleware)
+	profileGroup.GET("/photos", func(c echo.Context) error {
+		return routesV1Profile.GetPhotosHandler(c, photoCase)
+	})
+	profileGroup.POST("/photos", func(c echo.Context) error {
+		return routesV1Profile.UploadPhotoHandler(c, photoCase)
+	})
+	profileGroup.PUT("/photos/order", func(c echo.Context) error {
+		return routesV1Profile.ReorderPhotosHandler(c, photoCase)
+	})
+	profileGroup.PUT("/photos/:id/primary", func(c echo.Context) error {
+		return routesV1Profile.SetPrimaryPhotoHandler(c, photoCase)
+	})
+	profileGroup.DELETE("/photos/:id", func(c echo.Context) error {
+		return routesV1Profile.DeletePhotoHandler(c, photoCase)
 	})
 
+	matchGroup := v1.Group("/match", jwtMiddleware)
+	swipeLimit := rateLimiter.Limit(middleware.PolicySwipe)
+	matchGroup.GET("/profile", func(c echo.Context) error {
+		return routesV1Match.GetProfileHandler(c, matchCase)
+	}, rateLimiter.Limit(middleware.PolicyProfile))
+	matchGroup.POST("/profile/:id/like", func(c echo.Context) error {
+		return routesV1Match.LikeHandler(c, matchCase)
+	}, swipeLimit)
+
 	matchGroup.POST("/profile/:id/pass", func(c echo.Context) error {
-		return routesV1Match.PassHandler(c, matchCase, authCase)
+		return routesV1Match.PassHandler(c, matchCase)
+	}, swipeLimit)
+
+	v1.GET("/events", func(c echo.Context) error {
+		return routesV1Event.StreamHandler(c, eventCase)
+	}, jwtMiddleware)
+
+	deviceGroup := v1.Group("/devices", jwtMiddleware)
+	deviceGroup.POST("", func(c echo.Context) error {
+		return routesV1Notification.RegisterDeviceHandler(c, notificationCase)
+	})
+	deviceGroup.DELETE("/:token", func(c echo.Context) error {
+		return routesV1Notification.UnregisterDeviceHandler(c, notificationCase)
+	})
+
+	notificationGroup := v1.Group("/notifications", jwtMiddleware)
+	notificationGroup.GET("/preferences", func(c echo.Context) error {
+		return routesV1Notification.GetPreferencesHandler(c, notificationCase)
+	})
+	notificationGroup.PUT("/preferences", func(c echo.Context) error {
+		return routesV1Notification.UpdatePreferencesHandler(c, notificationCase)
+	})
+
+	blockGroup := v1.Group("/blocks", jwtMiddleware)
+	blockGroup.GET("", func(c echo.Context) error {
+		return routesV1Block.GetBlocksHandler(c, blockCase)
+	})
+	blockGroup.POST("/:id", func(c echo.Context) error {
+		return routesV1Block.BlockHandler(c, blockCase)
+	})
+	blockGroup.DELETE("/:id", func(c echo.Context) error {
+		return routesV1Block.UnblockHandler(c, blockCase)
+	})
+
+	v1.POST("/reports", func(c echo.Context) error {
+		return routesV1Report.CreateReportHandler(c, reportCase)
+	}, jwtMiddleware)
+
+	// Moderators review reports, photos and users, premium and quota changes are admin only
+	adminGroup := v1.Group("/admin", jwtMiddleware, middleware.AdminMiddleware(entity.RoleModerator))
+	adminOnly := middleware.AdminMiddleware(entity.RoleAdmin)
+	adminGroup.GET("/reports", func(c echo.Context) error {
+		return routesV1Admin.GetReportsHandler(c, reportCase)
+	})
+	adminGroup.GET("/reports/:id", func(c echo.Context) error {
+		return routesV1Admin.GetReportHandler(c, reportCase)
+	})
+	adminGroup.POST("/reports/:id/dismiss", func(c echo.Context) error {
+		return routesV1Admin.DismissReportHandler(c, reportCase)
+	})
+	adminGroup.POST("/reports/:id/action", func(c echo.Context) error {
+		return routesV1Admin.ActionReportHandler(c, reportCase)
+	})
+
+	adminGroup.GET("/photos", func(c echo.Context) error {
+		return routesV1Admin.GetPhotoReviewQueueHandler(c, photoCase)
+	})
+	adminGroup.POST("/photos/:id/approve", func(c echo.Context) error {
+		return routesV1Admin.ApprovePhotoHandler(c, photoCase)
+	})
+	adminGroup.POST("/photos/:id/reject", func(c echo.Context) error {
+		return routesV1Admin.RejectPhotoHandler(c, photoCase)
+	})
+
+	adminGroup.GET("/users", func(c echo.Context) error {
+		return routesV1Admin.FindUserHandler(c, adminCase)
+	})
+	adminGroup.GET("/users/:id", func(c echo.Context) error {
+		return routesV1Admin.GetUserHandler(c, adminCase)
+	})
+	adminGroup.GET("/users/:id/swipes", func(c echo.Context) error {
+		return routesV1Admin.GetSwipeHistoryHandler(c, adminCase)
+	})
+	adminGroup.POST("/users/:id/suspend", func(c echo.Context) error {
+		return routesV1Admin.SuspendUserHandler(c, adminCase)
 	})
+	adminGroup.PUT("/users/:id/premium", func(c echo.Context) error {
+		return routesV1Admin.SetPremiumHandler(c, adminCase)
+	}, adminOnly)
+	adminGroup.POST("/users/:id/like-quota/reset", func(c echo.Context) error {
+		return routesV1Admin.ResetLikeQuotaHandler(c, adminCase)
+	}, adminOnly)
 }
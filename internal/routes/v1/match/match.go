@@ -11,14 +11,14 @@ import (
 	"github.com/labstack/echo"
 )
 
-func GetProfileHandler(c echo.Context, matchCase match.IMatchUseCase, authCase authUseCase.IAuthUseCase) error {
+func GetProfileHandler(c echo.Context, matchCase match.IMatchUseCase) error {
 	request, err := http_util.Decode[entity.MatchGetProfileRequest](c)
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
-	user, err := authCase.GetUserFromJWTRequest(c)
+	user, err := authUseCase.UserFromContext(c.Request().Context())
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
@@ -38,14 +38,14 @@ func GetProfileHandler(c echo.Context, matchCase match.IMatchUseCase, authCase a
 	})
 }
 
-func LikeHandler(c echo.Context, matchCase match.IMatchUseCase, authCase authUseCase.IAuthUseCase) error {
+func LikeHandler(c echo.Context, matchCase match.IMatchUseCase) error {
 	likeRequest, err := http_util.Decode[entity.MatchLikeRequest](c)
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
-	user, err := authCase.GetUserFromJWTRequest(c)
+	user, err := authUseCase.UserFromContext(c.Request().Context())
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
@@ -78,8 +78,8 @@ func LikeHandler(c echo.Context, matchCase match.IMatchUseCase, authCase authUse
 	})
 }
 
-func PassHandler(c echo.Context, matchCase match.IMatchUseCase, authCase authUseCase.IAuthUseCase) error {
-	user, err := authCase.GetUserFromJWTRequest(c)
+func PassHandler(c echo.Context, matchCase match.IMatchUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
 
 	if err != nil {
 		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
@@ -0,0 +1,114 @@
+package routesV1Notification
+
+import (
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	notificationUseCase "github.com/ghaniswara/dating-app/internal/usecase/notification"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+func RegisterDeviceHandler(c echo.Context, notificationCase notificationUseCase.INotificationUseCase) error {
+	reqBody, err := http_util.Decode[entity.RegisterDeviceRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	device, err := notificationCase.RegisterDevice(c.Request().Context(), int(user.ID), reqBody)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to register device"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.DeviceResponse]{
+		Message: "Device registered",
+		Data: entity.DeviceResponse{
+			ID:       int(device.ID),
+			Platform: device.Platform,
+		},
+	})
+}
+
+func UnregisterDeviceHandler(c echo.Context, notificationCase notificationUseCase.INotificationUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	if err := notificationCase.UnregisterDevice(c.Request().Context(), int(user.ID), c.Param("token")); err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to unregister device"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Device unregistered",
+	})
+}
+
+func GetPreferencesHandler(c echo.Context, notificationCase notificationUseCase.INotificationUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	preference, err := notificationCase.GetPreferences(c.Request().Context(), int(user.ID))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get preferences"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.NotificationPreferencesResponse]{
+		Message: "Notification preferences",
+		Data:    toPreferencesResponse(preference),
+	})
+}
+
+func UpdatePreferencesHandler(c echo.Context, notificationCase notificationUseCase.INotificationUseCase) error {
+	reqBody, err := http_util.Decode[entity.UpdateNotificationPreferencesRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	preference, err := notificationCase.UpdatePreferences(c.Request().Context(), int(user.ID), reqBody)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to update preferences"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.NotificationPreferencesResponse]{
+		Message: "Notification preferences updated",
+		Data:    toPreferencesResponse(preference),
+	})
+}
+
+func toPreferencesResponse(preference *entity.NotificationPreference) entity.NotificationPreferencesResponse {
+	return entity.NotificationPreferencesResponse{
+		Match:     preference.Match,
+		SuperLike: preference.SuperLike,
+	}
+}
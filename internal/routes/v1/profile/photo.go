@@ -0,0 +1,210 @@
+package routesV1Profile
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+
+	"github.com/labstack/echo"
+)
+
+// Room for the multipart headers around the file
+const multipartOverhead = 1 << 20
+
+// Multipart form with the image in the photo field
+func UploadPhotoHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, photoUseCase.MaxPhotoSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("photo")
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return encodePhotoTooLarge(c)
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "photo", Detail: "photo is required"}},
+		})
+	}
+
+	file, err := fileHeader.Open()
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, photoUseCase.MaxPhotoSize+1))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	photo, err := photoCase.UploadPhoto(c.Request().Context(), int(user.ID), data)
+
+	if errors.Is(err, photoUseCase.ErrPhotoTooLarge) {
+		return encodePhotoTooLarge(c)
+	}
+
+	if errors.Is(err, photoUseCase.ErrInvalidPhoto) {
+		return http_util.Encode(c, http.StatusUnsupportedMediaType, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "photo", Detail: "photo should be a JPEG or PNG image"}},
+		})
+	}
+
+	if errors.Is(err, photoUseCase.ErrPhotoLimit) {
+		return http_util.Encode(c, http.StatusConflict, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "photo", Detail: "photo limit reached, delete a photo first"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to upload photo"})
+	}
+
+	return http_util.Encode(c, http.StatusCreated, http_util.HTTPResponse[entity.PhotoResponse]{
+		Message: "Photo uploaded",
+		Data:    entity.NewPhotoResponse(*photo),
+	})
+}
+
+func GetPhotosHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	photos, err := photoCase.GetPhotos(c.Request().Context(), int(user.ID))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to get photos"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.PhotoListResponse]{
+		Message: "Photos",
+		Data:    newPhotoListResponse(photos),
+	})
+}
+
+func ReorderPhotosHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	reqBody, err := http_util.Decode[entity.ReorderPhotosRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	photos, err := photoCase.ReorderPhotos(c.Request().Context(), int(user.ID), reqBody.PhotoIDs)
+
+	if errors.Is(err, photoUseCase.ErrInvalidPhotoSet) {
+		return http_util.Encode(c, http.StatusBadRequest, http_util.HTTPErrorResponse[any]{
+			Errors: []http_util.ErrorResponse{{Property: "photo_ids", Detail: "photo_ids should list every photo once"}},
+		})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to reorder photos"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.PhotoListResponse]{
+		Message: "Photos reordered",
+		Data:    newPhotoListResponse(photos),
+	})
+}
+
+func SetPrimaryPhotoHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	photoID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	err = photoCase.SetPrimaryPhoto(c.Request().Context(), int(user.ID), photoID)
+
+	if errors.Is(err, photoUseCase.ErrPhotoNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "photo not found"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to set primary photo"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Primary photo set",
+	})
+}
+
+func DeletePhotoHandler(c echo.Context, photoCase photoUseCase.IPhotoUseCase) error {
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	photoID, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	err = photoCase.DeletePhoto(c.Request().Context(), int(user.ID), photoID)
+
+	if errors.Is(err, photoUseCase.ErrPhotoNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "photo not found"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to delete photo"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.JSONResponse{
+		Message: "Photo deleted",
+	})
+}
+
+func encodePhotoTooLarge(c echo.Context) error {
+	return http_util.Encode(c, http.StatusRequestEntityTooLarge, http_util.HTTPErrorResponse[any]{
+		Errors: []http_util.ErrorResponse{{Property: "photo", Detail: "photo should be at most 10MB"}},
+	})
+}
+
+func newPhotoListResponse(photos []entity.Photo) entity.PhotoListResponse {
+	response := entity.PhotoListResponse{Photos: []entity.PhotoResponse{}}
+	for _, photo := range photos {
+		response.Photos = append(response.Photos, entity.NewPhotoResponse(photo))
+	}
+	return response
+}
@@ -0,0 +1,53 @@
+package routesV1Report
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	reportUseCase "github.com/ghaniswara/dating-app/internal/usecase/report"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/labstack/echo"
+)
+
+func CreateReportHandler(c echo.Context, reportCase reportUseCase.IReportUseCase) error {
+	reqBody, err := http_util.Decode[entity.CreateReportRequest](c)
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	problems := reqBody.Validate(c.Request().Context())
+
+	if len(problems) != 0 {
+		return http_util.Encode(c, 400, http_util.JSONResponse{
+			Message: "Bad request check your request",
+		})
+	}
+
+	user, err := authUseCase.UserFromContext(c.Request().Context())
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	report, err := reportCase.ReportUser(c.Request().Context(), int(user.ID), reqBody)
+
+	if errors.Is(err, reportUseCase.ErrReportSelf) {
+		return http_util.Encode(c, http.StatusBadRequest, map[string]string{"error": "cannot report yourself"})
+	}
+
+	if errors.Is(err, reportUseCase.ErrUserNotFound) {
+		return http_util.Encode(c, http.StatusNotFound, map[string]string{"error": "user not found"})
+	}
+
+	if err != nil {
+		return http_util.Encode(c, http.StatusInternalServerError, map[string]string{"error": "failed to create report"})
+	}
+
+	return http_util.Encode(c, http.StatusOK, http_util.HTTPResponse[entity.ReportResponse]{
+		Message: "Report submitted",
+		Data:    entity.NewReportResponse(*report),
+	})
+}
@@ -2,20 +2,60 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ghaniswara/dating-app/internal/config"
 	"github.com/ghaniswara/dating-app/internal/datastore/postgres"
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/events"
+	"github.com/ghaniswara/dating-app/internal/middleware"
+	authRepo "github.com/ghaniswara/dating-app/internal/repository/auth"
+	blockRepo "github.com/ghaniswara/dating-app/internal/repository/block"
+	eventRepo "github.com/ghaniswara/dating-app/internal/repository/event"
+	exportRepo "github.com/ghaniswara/dating-app/internal/repository/export"
 	matchRepo "github.com/ghaniswara/dating-app/internal/repository/match"
+	mfaRepo "github.com/ghaniswara/dating-app/internal/repository/mfa"
+	notificationRepo "github.com/ghaniswara/dating-app/internal/repository/notification"
+	outboxRepo "github.com/ghaniswara/dating-app/internal/repository/outbox"
+	photoRepo "github.com/ghaniswara/dating-app/internal/repository/photo"
+	reportRepo "github.com/ghaniswara/dating-app/internal/repository/report"
+	securityRepo "github.com/ghaniswara/dating-app/internal/repository/security"
+	tokenRepo "github.com/ghaniswara/dating-app/internal/repository/token"
 	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
 	routesV1 "github.com/ghaniswara/dating-app/internal/routes/v1"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	adminUseCase "github.com/ghaniswara/dating-app/internal/usecase/admin"
 	authUseCase "github.com/ghaniswara/dating-app/internal/usecase/auth"
+	blockUseCase "github.com/ghaniswara/dating-app/internal/usecase/block"
+	eventUseCase "github.com/ghaniswara/dating-app/internal/usecase/event"
+	exportUseCase "github.com/ghaniswara/dating-app/internal/usecase/export"
 	"github.com/ghaniswara/dating-app/internal/usecase/match"
+	notificationUseCase "github.com/ghaniswara/dating-app/internal/usecase/notification"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	reportUseCase "github.com/ghaniswara/dating-app/internal/usecase/report"
+	accountWorker "github.com/ghaniswara/dating-app/internal/worker/account"
+	exportWorker "github.com/ghaniswara/dating-app/internal/worker/export"
+	matchWorker "github.com/ghaniswara/dating-app/internal/worker/match"
+	notificationWorker "github.com/ghaniswara/dating-app/internal/worker/notification"
+	outboxWorker "github.com/ghaniswara/dating-app/internal/worker/outbox"
+	photoWorker "github.com/ghaniswara/dating-app/internal/worker/photo"
+	"github.com/ghaniswara/dating-app/pkg/clientip"
+	"github.com/ghaniswara/dating-app/pkg/jwt"
+	"github.com/ghaniswara/dating-app/pkg/mail"
+	"github.com/ghaniswara/dating-app/pkg/moderation"
+	"github.com/ghaniswara/dating-app/pkg/oidc"
+	"github.com/ghaniswara/dating-app/pkg/push"
+	"github.com/ghaniswara/dating-app/pkg/ratelimit"
+	"github.com/ghaniswara/dating-app/pkg/sms"
+	"github.com/ghaniswara/dating-app/pkg/storage"
 	"github.com/go-redis/redis"
 	"github.com/labstack/echo"
 	"gorm.io/gorm"
@@ -24,7 +64,18 @@ import (
 func Run(ctx context.Context, w io.Writer, args []string) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
-	server := NewServer(ctx, w, args[0])
+
+	server, err := NewServer(ctx, w, args[0])
+	if err != nil {
+		return err
+	}
+
+	return Serve(ctx, w, server)
+}
+
+// Start the workers and the HTTP server, it shuts down once ctx is cancelled
+func Serve(ctx context.Context, w io.Writer, server *Server) error {
+	server.StartWorkers(ctx)
 
 	go func() {
 		if err := server.StartServer(); err != nil && err != http.ErrServerClosed {
@@ -47,14 +98,34 @@ func Run(ctx context.Context, w io.Writer, args []string) error {
 }
 
 type Server struct {
-	httpServer   *http.Server
-	database     *gorm.DB
-	authUseCase  authUseCase.IAuthUseCase
-	matchUseCase match.IMatchUseCase
-	userRepo     userRepo.IUserRepo
+	httpServer          *http.Server
+	database            *gorm.DB
+	authUseCase         authUseCase.IAuthUseCase
+	matchUseCase        match.IMatchUseCase
+	eventUseCase        eventUseCase.IEventUseCase
+	notificationUseCase notificationUseCase.INotificationUseCase
+	blockUseCase        blockUseCase.IBlockUseCase
+	reportUseCase       reportUseCase.IReportUseCase
+	adminUseCase        adminUseCase.IAdminUseCase
+	accountUseCase      accountUseCase.IAccountUseCase
+	exportUseCase       exportUseCase.IExportUseCase
+	photoUseCase        photoUseCase.IPhotoUseCase
+	notificationWorker  *notificationWorker.Dispatcher
+	outboxWorker        *outboxWorker.Relay
+	matchWorker         *matchWorker.Consumer
+	accountWorker       *accountWorker.Eraser
+	exportWorker        *exportWorker.Builder
+	photoWorker         *photoWorker.Moderator
+	userRepo            userRepo.IUserRepo
+	jwtManager          *jwt.Manager
+	rateLimiter         *middleware.RateLimiter
+	pushProviders       map[entity.Platform]push.Provider
+	mailer              mail.Mailer
 }
 
-func NewServer(ctx context.Context, w io.Writer, env string) *Server {
+// NewServer wires the dependencies from the env's configuration, it fails when
+// a dependency can't be set up rather than starting half configured
+func NewServer(ctx context.Context, w io.Writer, env string) (*Server, error) {
 	e := echo.New()
 
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -67,10 +138,17 @@ func NewServer(ctx context.Context, w io.Writer, env string) *Server {
 	config, err := config.NewConfig(env)
 
 	if err != nil {
-		fmt.Fprint(w, "Error loading configurations:", err)
-		ctx.Err()
+		return nil, fmt.Errorf("error loading configurations: %w", err)
 	}
 
+	ipResolver, err := clientip.NewResolver(strings.Split(config.Get("TRUSTED_PROXIES"), ","))
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing trusted proxies: %w", err)
+	}
+
+	e.Use(middleware.ClientIPMiddleware(ipResolver))
+
 	database, err := postgres.InitializeDB(
 		config.Get("POSTGRES_USER"),
 		config.Get("POSTGRES_PASSWORD"),
@@ -80,23 +158,106 @@ func NewServer(ctx context.Context, w io.Writer, env string) *Server {
 	)
 
 	if err != nil {
-		fmt.Fprint(w, "Error initializing database:", err)
-		ctx.Err()
+		return nil, fmt.Errorf("error initializing database: %w", err)
 	}
 
 	redis := redis.NewClient(&redis.Options{
 		Addr: config.Get("REDIS_HOST") + ":" + config.Get("REDIS_PORT"),
 	})
 
-	userRepo := userRepo.New(database)
+	streamRedis, err := newStreamRedis(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing redis stream client: %w", err)
+	}
+
+	userRepo := userRepo.New(database, redis)
 	matchRepo := matchRepo.NewMatchRepo(database, redis)
-	authUC := authUseCase.New(userRepo)
+	eventRepo := eventRepo.NewEventRepo(redis, streamRedis)
+	notificationRepo := notificationRepo.NewNotificationRepo(database, redis)
+	outboxRepo := outboxRepo.NewOutboxRepo(database)
+	blockRepo := blockRepo.NewBlockRepo(database)
+	reportRepo := reportRepo.NewReportRepo(database)
+	tokenRepo := tokenRepo.NewTokenRepo(database, redis)
+	securityRepo := securityRepo.NewSecurityRepo(database, redis)
+	mfaRepo := mfaRepo.NewMFARepo(database, redis)
+	authRepo := authRepo.NewAuthRepo(database, redis)
+	exportRepo := exportRepo.NewExportRepo(database)
+	photoRepo := photoRepo.NewPhotoRepo(database)
+
+	jwtManager, err := newJWTManager(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing jwt keys: %w", err)
+	}
+
+	oidcProviders, err := newOIDCProviders(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing oidc providers: %w", err)
+	}
+
+	mailer, err := newMailer(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing mailer: %w", err)
+	}
+
+	moderator, err := newModerator(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing photo moderator: %w", err)
+	}
+
+	exportSigningKey, err := newExportSigningKey(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing data exports: %w", err)
+	}
+
+	authUC := authUseCase.New(
+		userRepo,
+		tokenRepo,
+		securityRepo,
+		mfaRepo,
+		authRepo,
+		jwtManager,
+		mailer,
+		newSMSSender(config),
+		config.Get("APP_URL"),
+		oidcProviders,
+	)
+	eventUC := eventUseCase.New(eventRepo)
+	notificationUC := notificationUseCase.New(notificationRepo, userRepo)
+	blockUC := blockUseCase.New(blockRepo, matchRepo, userRepo)
+	accountUC := accountUseCase.New(userRepo, tokenRepo, matchRepo)
+	reportUC := reportUseCase.New(reportRepo, userRepo, accountUC)
+	adminUC := adminUseCase.New(userRepo, matchRepo, accountUC)
+	photoUC := photoUseCase.New(photoRepo, newBlobStore(config, e), moderator)
+	exportUC := exportUseCase.New(exportRepo, photoUC, exportSigningKey, config.Get("APP_URL"))
 	matchUC := match.NewMatchUseCase(
 		userRepo,
 		redis,
 		matchRepo,
+		blockRepo,
+		reportRepo,
+		photoUC,
 	)
 
+	rateLimiter, err := newRateLimiter(config, redis)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing rate limits: %w", err)
+	}
+
+	pushProviders, err := newPushProviders(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("error initializing push providers: %w", err)
+	}
+
+	subscriber := events.NewRedisStreamSubscriber(redis, events.RedisStreamSubscriberOptions{})
+
 	var PORT = config.Get("PORT")
 
 	server := &Server{
@@ -104,19 +265,62 @@ func NewServer(ctx context.Context, w io.Writer, env string) *Server {
 			Addr:    ":" + PORT,
 			Handler: e,
 		},
-		database:     database,
-		authUseCase:  authUC,
-		matchUseCase: matchUC,
-		userRepo:     userRepo,
+		database:            database,
+		authUseCase:         authUC,
+		matchUseCase:        matchUC,
+		eventUseCase:        eventUC,
+		notificationUseCase: notificationUC,
+		blockUseCase:        blockUC,
+		reportUseCase:       reportUC,
+		adminUseCase:        adminUC,
+		accountUseCase:      accountUC,
+		exportUseCase:       exportUC,
+		photoUseCase:        photoUC,
+		notificationWorker:  notificationWorker.NewDispatcher(notificationRepo, pushProviders),
+		outboxWorker:        outboxWorker.NewRelay(outboxRepo, events.NewRedisStreamPublisher(redis)),
+		matchWorker:         matchWorker.NewConsumer(subscriber, events.NewRedisDeduplicator(redis), eventUC, notificationUC),
+		accountWorker:       accountWorker.NewEraser(userRepo, photoUC),
+		exportWorker:        exportWorker.NewBuilder(exportUC),
+		photoWorker:         photoWorker.NewModerator(photoUC),
+		userRepo:            userRepo,
+		jwtManager:          jwtManager,
+		rateLimiter:         rateLimiter,
+		pushProviders:       pushProviders,
+		mailer:              mailer,
 	}
 
 	server.RegisterRoutes(e)
-	return server
+	return server, nil
 }
 
 func (s *Server) RegisterRoutes(e *echo.Echo) {
 	e.GET("/health", s.handleHealthCheck)
-	routesV1.InitV1Routes(e, s.authUseCase, s.matchUseCase, s.userRepo)
+	e.GET("/.well-known/jwks.json", s.handleJWKS)
+	routesV1.InitV1Routes(
+		e,
+		s.authUseCase,
+		s.matchUseCase,
+		s.eventUseCase,
+		s.notificationUseCase,
+		s.blockUseCase,
+		s.reportUseCase,
+		s.adminUseCase,
+		s.accountUseCase,
+		s.exportUseCase,
+		s.photoUseCase,
+		s.userRepo,
+		s.rateLimiter,
+	)
+}
+
+// Background workers stop when ctx is cancelled
+func (s *Server) StartWorkers(ctx context.Context) {
+	go s.notificationWorker.Run(ctx)
+	go s.outboxWorker.Run(ctx)
+	go s.matchWorker.Run(ctx)
+	go s.accountWorker.Run(ctx)
+	go s.exportWorker.Run(ctx)
+	go s.photoWorker.Run(ctx)
 }
 
 func (s *Server) StartServer() error {
@@ -128,8 +332,283 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// Tests read what the fake provider sent through it
+func (s *Server) PushProvider(platform entity.Platform) push.Provider {
+	return s.pushProviders[platform]
+}
+
+// The configured mailer, tests read the fake one's sent messages through it
+func (s *Server) Mailer() mail.Mailer {
+	return s.mailer
+}
+
 func (s *Server) handleHealthCheck(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{
 		"status": "healthy",
 	})
 }
+
+// Public keys clients can use to verify access tokens, empty with HS256
+func (s *Server) handleJWKS(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.jwtManager.JWKS())
+}
+
+// JWT_KEYS lists "kid=value" pairs separated by commas, the value is the
+// secret for HS256 or the path to a PEM private key for RS256 and EdDSA.
+// Without JWT_KEYS, JWT_SECRET is used as a single HS256 key. The active key
+// signs new tokens, defaulting to the first one, the others only verify
+func newJWTManager(config *config.Config) (*jwt.Manager, error) {
+	algorithm := config.Get("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = jwt.AlgorithmHS256
+	}
+
+	entries := strings.Split(config.Get("JWT_KEYS"), ",")
+	if config.Get("JWT_KEYS") == "" {
+		entries = []string{"default=" + config.Get("JWT_SECRET")}
+	}
+
+	var keys []jwt.Key
+
+	for _, entry := range entries {
+		id, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid jwt key entry %q", entry)
+		}
+
+		var key jwt.Key
+		var err error
+
+		if algorithm == jwt.AlgorithmHS256 {
+			key, err = jwt.NewHMACKey(id, []byte(value))
+		} else {
+			var keyPEM []byte
+			keyPEM, err = os.ReadFile(value)
+			if err != nil {
+				return nil, err
+			}
+			key, err = jwt.NewKeyFromPEM(algorithm, id, keyPEM)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	activeKeyID := config.Get("JWT_ACTIVE_KEY_ID")
+	if activeKeyID == "" {
+		activeKeyID = keys[0].ID
+	}
+
+	return jwt.NewManager(activeKeyID, keys...)
+}
+
+// Download links are signed with it, a short or missing key would let anyone
+// forge a link to someone's personal data
+func newExportSigningKey(config *config.Config) (string, error) {
+	key := config.Get("EXPORT_SIGNING_KEY")
+
+	if len(key) < 32 {
+		return "", errors.New("EXPORT_SIGNING_KEY needs at least 32 bytes")
+	}
+
+	return key, nil
+}
+
+// Client for the blocking reads of event streams, each open stream holds one of
+// its <ENV>_REDIS_STREAM_POOL_SIZE connections. A stream opened while they're
+// all taken fails fast and the client reconnects later
+func newStreamRedis(config *config.Config) (*redis.Client, error) {
+	poolSize, err := strconv.Atoi(config.Get("REDIS_STREAM_POOL_SIZE"))
+	if err != nil || poolSize <= 0 {
+		return nil, fmt.Errorf("invalid redis stream pool size %q", config.Get("REDIS_STREAM_POOL_SIZE"))
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:        config.Get("REDIS_HOST") + ":" + config.Get("REDIS_PORT"),
+		PoolSize:    poolSize,
+		PoolTimeout: time.Second,
+	}), nil
+}
+
+// Policies are read from <ENV>_RATE_LIMIT_<POLICY> as "<limit>/<window>"
+func newRateLimiter(config *config.Config, rdb *redis.Client) (*middleware.RateLimiter, error) {
+	if config.Get("RATE_LIMIT_ENABLED") == "false" {
+		return middleware.NewRateLimiter(nil, nil), nil
+	}
+
+	policies := map[string]ratelimit.Policy{}
+
+	for name, key := range map[string]string{
+		middleware.PolicyDefault: "RATE_LIMIT_DEFAULT",
+		middleware.PolicySignUp:  "RATE_LIMIT_SIGN_UP",
+		middleware.PolicySignIn:  "RATE_LIMIT_SIGN_IN",
+		middleware.PolicySwipe:   "RATE_LIMIT_SWIPE",
+		middleware.PolicyProfile: "RATE_LIMIT_PROFILE",
+	} {
+		policy, err := ratelimit.ParsePolicy(name, config.Get(key))
+		if err != nil {
+			return nil, err
+		}
+		policies[name] = policy
+	}
+
+	return middleware.NewRateLimiter(ratelimit.NewRedisLimiter(rdb), policies), nil
+}
+
+// Providers with a client ID configured, callbacks go to
+// <OIDC_CALLBACK_URL>/<provider>/callback
+func newOIDCProviders(config *config.Config) (map[string]*oidc.Provider, error) {
+	providers := map[string]*oidc.Provider{}
+	callbackURL := strings.TrimSuffix(config.Get("OIDC_CALLBACK_URL"), "/")
+
+	if clientID := config.Get("OIDC_GOOGLE_CLIENT_ID"); clientID != "" {
+		providers["google"] = oidc.NewProvider(oidc.Config{
+			Issuer:       "https://accounts.google.com",
+			ClientID:     clientID,
+			ClientSecret: config.Get("OIDC_GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  callbackURL + "/google/callback",
+		}, nil)
+	}
+
+	// Apple's client secret is a JWT signed with the team's key, it's
+	// generated outside the app and has to be renewed before it expires
+	if clientID := config.Get("OIDC_APPLE_CLIENT_ID"); clientID != "" {
+		providers["apple"] = oidc.NewProvider(oidc.Config{
+			Issuer:       "https://appleid.apple.com",
+			ClientID:     clientID,
+			ClientSecret: config.Get("OIDC_APPLE_CLIENT_SECRET"),
+			RedirectURL:  callbackURL + "/apple/callback",
+			Scopes:       []string{"openid", "email", "name"},
+			ResponseMode: "form_post",
+		}, nil)
+	}
+
+	if config.Get("OIDC_FAKE_ENABLED") == "true" {
+		provider, err := newFakeOIDCProvider(callbackURL + "/fake/callback")
+		if err != nil {
+			return nil, err
+		}
+
+		providers["fake"] = provider
+	}
+
+	return providers, nil
+}
+
+// The fake mailer has to be asked for, a missing or misspelled provider would
+// otherwise drop every verification and reset email without a trace
+func newMailer(config *config.Config) (mail.Mailer, error) {
+	switch provider := config.Get("MAIL_PROVIDER"); provider {
+	case "smtp":
+		if config.Get("SMTP_HOST") == "" || config.Get("MAIL_FROM") == "" {
+			return nil, errors.New("smtp mail provider needs SMTP_HOST and MAIL_FROM")
+		}
+
+		return mail.NewSMTPMailer(
+			config.Get("SMTP_HOST"),
+			config.Get("SMTP_PORT"),
+			config.Get("SMTP_USERNAME"),
+			config.Get("SMTP_PASSWORD"),
+			config.Get("MAIL_FROM"),
+		), nil
+	case "fake":
+		return mail.NewFakeMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", provider)
+	}
+}
+
+func newSMSSender(config *config.Config) sms.Sender {
+	if config.Get("SMS_PROVIDER") == "twilio" {
+		return sms.NewTwilioSender(
+			config.Get("TWILIO_ACCOUNT_SID"),
+			config.Get("TWILIO_AUTH_TOKEN"),
+			config.Get("SMS_FROM"),
+		)
+	}
+
+	return sms.NewFakeSender()
+}
+
+// The filesystem store is served by the server itself at /media
+func newBlobStore(config *config.Config, e *echo.Echo) storage.BlobStore {
+	if config.Get("STORAGE_PROVIDER") == "s3" {
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        config.Get("S3_ENDPOINT"),
+			Region:          config.Get("S3_REGION"),
+			Bucket:          config.Get("S3_BUCKET"),
+			AccessKeyID:     config.Get("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: config.Get("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       config.Get("STORAGE_PUBLIC_URL"),
+		})
+	}
+
+	publicURL := config.Get("STORAGE_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = strings.TrimSuffix(config.Get("APP_URL"), "/") + "/media"
+	}
+
+	e.Static("/media", config.Get("STORAGE_DIR"))
+
+	return storage.NewFilesystemStore(config.Get("STORAGE_DIR"), publicURL)
+}
+
+// Every photo waits for a reviewer, the rules only reject or flag ahead of the review
+func newModerator(config *config.Config) (moderation.Moderator, error) {
+	switch moderator := config.Get("PHOTO_MODERATOR"); moderator {
+	case "manual":
+		return moderation.NewManualModerator(), nil
+	case "rules":
+		return moderation.NewRuleModerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown photo moderator %q", moderator)
+	}
+}
+
+func newPushProviders(config *config.Config) (map[entity.Platform]push.Provider, error) {
+	providers := map[entity.Platform]push.Provider{}
+
+	if config.Get("PUSH_PROVIDER") == "fake" {
+		fake := push.NewFakeProvider()
+		providers[entity.PlatformFCM] = fake
+		providers[entity.PlatformAPNs] = fake
+		return providers, nil
+	}
+
+	if credentialsFile := config.Get("FCM_CREDENTIALS_FILE"); credentialsFile != "" {
+		credentials, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return providers, err
+		}
+
+		fcm, err := push.NewFCMProvider(credentials)
+		if err != nil {
+			return providers, err
+		}
+		providers[entity.PlatformFCM] = fcm
+	}
+
+	if keyFile := config.Get("APNS_KEY_FILE"); keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return providers, err
+		}
+
+		apns, err := push.NewAPNsProvider(
+			key,
+			config.Get("APNS_KEY_ID"),
+			config.Get("APNS_TEAM_ID"),
+			config.Get("APNS_TOPIC"),
+			config.Get("APNS_PRODUCTION") == "true",
+		)
+		if err != nil {
+			return providers, err
+		}
+		providers[entity.PlatformAPNs] = apns
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,114 @@
+package accountUseCase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	matchRepo "github.com/ghaniswara/dating-app/internal/repository/match"
+	tokenRepo "github.com/ghaniswara/dating-app/internal/repository/token"
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	"github.com/ghaniswara/dating-app/pkg/jwt"
+)
+
+// Time a deleted account can still be restored by signing in, it's erased afterwards
+const DeletionGracePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrAccountSuspended = errors.New("account suspended")
+	ErrAccountBanned    = errors.New("account banned")
+	ErrAccountDeleted   = errors.New("account deleted")
+
+	ErrInsufficientRole = errors.New("cannot moderate a user with an equal or higher role")
+)
+
+type IAccountUseCase interface {
+	SuspendUser(ctx context.Context, userID int, until time.Time, reason string, actorID *uint) error
+	BanUser(ctx context.Context, userID int, reason string, actorID *uint) error
+	ReactivateUser(ctx context.Context, userID int, reason string, actorID *uint) error
+	GetStatusHistory(ctx context.Context, userID int) ([]entity.AccountStatusAudit, error)
+
+	// Delete the account right away, it's hidden from everyone, signed out
+	// and unmatched. The data is erased after DeletionGracePeriod unless the
+	// user signs in again before then. Returns when it will be erased
+	DeleteAccount(ctx context.Context, userID int) (time.Time, error)
+}
+
+type accountUseCase struct {
+	userRepo  userRepo.IUserRepo
+	tokenRepo tokenRepo.ITokenRepo
+	matchRepo matchRepo.IMatchRepo
+}
+
+func New(userRepo userRepo.IUserRepo, tokenRepo tokenRepo.ITokenRepo, matchRepo matchRepo.IMatchRepo) IAccountUseCase {
+	return &accountUseCase{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		matchRepo: matchRepo,
+	}
+}
+
+func (a *accountUseCase) SuspendUser(ctx context.Context, userID int, until time.Time, reason string, actorID *uint) error {
+	return a.userRepo.UpdateAccountStatus(ctx, userID, entity.AccountSuspended, &until, reason, actorID)
+}
+
+func (a *accountUseCase) BanUser(ctx context.Context, userID int, reason string, actorID *uint) error {
+	return a.userRepo.UpdateAccountStatus(ctx, userID, entity.AccountBanned, nil, reason, actorID)
+}
+
+func (a *accountUseCase) ReactivateUser(ctx context.Context, userID int, reason string, actorID *uint) error {
+	return a.userRepo.UpdateAccountStatus(ctx, userID, entity.AccountActive, nil, reason, actorID)
+}
+
+func (a *accountUseCase) GetStatusHistory(ctx context.Context, userID int) ([]entity.AccountStatusAudit, error) {
+	return a.userRepo.GetAccountStatusAudits(ctx, userID)
+}
+
+func (a *accountUseCase) DeleteAccount(ctx context.Context, userID int) (time.Time, error) {
+	eraseAt := time.Now().Add(DeletionGracePeriod)
+	actorID := uint(userID)
+
+	if err := a.userRepo.ScheduleDeletion(ctx, userID, eraseAt, "deleted by the user", &actorID); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := a.tokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := a.tokenRepo.RevokeUserAccessTokens(ctx, userID, time.Now(), jwt.AccessTokenTTL); err != nil {
+		return time.Time{}, err
+	}
+
+	return eraseAt, a.matchRepo.EndAllMatches(ctx, userID)
+}
+
+// CheckModerator refuses moderation of a target whose role is equal to or
+// above the actor's, so moderators can't act on each other or on admins
+func CheckModerator(actor *entity.User, target *entity.User) error {
+	if !actor.Role.Outranks(target.Role) {
+		return ErrInsufficientRole
+	}
+
+	return nil
+}
+
+// CheckStatus maps the effective account status to one of the ErrAccount
+// errors, nil when the user can use the service
+func CheckStatus(user *entity.User, now time.Time) error {
+	switch user.AccountStatusAt(now) {
+	case entity.AccountActive:
+		return nil
+	case entity.AccountSuspended:
+		if user.SuspendedUntil != nil {
+			return fmt.Errorf("%w until %s", ErrAccountSuspended, user.SuspendedUntil.Format(time.RFC3339))
+		}
+		return ErrAccountSuspended
+	case entity.AccountBanned:
+		return ErrAccountBanned
+	default:
+		return ErrAccountDeleted
+	}
+}
@@ -0,0 +1,120 @@
+package adminUseCase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	matchRepo "github.com/ghaniswara/dating-app/internal/repository/match"
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	"gorm.io/gorm"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type IAdminUseCase interface {
+	GetUser(ctx context.Context, userID int) (*entity.User, error)
+	FindUser(ctx context.Context, email, username string) (*entity.User, error)
+	SetPremium(ctx context.Context, userID int, isPremium bool) (*entity.User, error)
+
+	// The actor is recorded in the status audit log and must outrank the user
+	SuspendUser(ctx context.Context, userID int, request entity.SuspendUserRequest, actor *entity.User) (*entity.User, error)
+	GetSwipeHistory(ctx context.Context, userID int, limit int, offset int) ([]entity.SwipeTransaction, error)
+	ResetLikeQuota(ctx context.Context, userID int) error
+}
+
+type adminUseCase struct {
+	userRepo    userRepo.IUserRepo
+	matchRepo   matchRepo.IMatchRepo
+	accountCase accountUseCase.IAccountUseCase
+}
+
+func New(userRepo userRepo.IUserRepo, matchRepo matchRepo.IMatchRepo, accountCase accountUseCase.IAccountUseCase) IAdminUseCase {
+	return &adminUseCase{
+		userRepo:    userRepo,
+		matchRepo:   matchRepo,
+		accountCase: accountCase,
+	}
+}
+
+func (a *adminUseCase) GetUser(ctx context.Context, userID int) (*entity.User, error) {
+	user, err := a.userRepo.GetUserByID(ctx, userID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	return user, err
+}
+
+func (a *adminUseCase) FindUser(ctx context.Context, email, username string) (*entity.User, error) {
+	if email == "" && username == "" {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := a.userRepo.GetUserByUnameOrEmail(ctx, email, username)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	return user, err
+}
+
+func (a *adminUseCase) SetPremium(ctx context.Context, userID int, isPremium bool) (*entity.User, error) {
+	err := a.userRepo.UpdatePremium(ctx, userID, isPremium)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.userRepo.GetUserByID(ctx, userID)
+}
+
+func (a *adminUseCase) SuspendUser(ctx context.Context, userID int, request entity.SuspendUserRequest, actor *entity.User) (*entity.User, error) {
+	target, err := a.GetUser(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := accountUseCase.CheckModerator(actor, target); err != nil {
+		return nil, err
+	}
+
+	until := time.Now().AddDate(0, 0, request.Days)
+
+	err = a.accountCase.SuspendUser(ctx, userID, until, request.Reason, &actor.ID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.userRepo.GetUserByID(ctx, userID)
+}
+
+func (a *adminUseCase) GetSwipeHistory(ctx context.Context, userID int, limit int, offset int) ([]entity.SwipeTransaction, error) {
+	if _, err := a.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return a.matchRepo.GetSwipeHistory(ctx, userID, limit, offset)
+}
+
+func (a *adminUseCase) ResetLikeQuota(ctx context.Context, userID int) error {
+	if _, err := a.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return a.matchRepo.ResetTodayLikesCount(ctx, userID)
+}
@@ -2,84 +2,441 @@ package authUseCase
 
 import (
 	"context"
-	"net/http"
-	"strings"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
 
 	"github.com/ghaniswara/dating-app/internal/entity"
+	authRepo "github.com/ghaniswara/dating-app/internal/repository/auth"
+	mfaRepo "github.com/ghaniswara/dating-app/internal/repository/mfa"
+	securityRepo "github.com/ghaniswara/dating-app/internal/repository/security"
+	tokenRepo "github.com/ghaniswara/dating-app/internal/repository/token"
 	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
 	"github.com/ghaniswara/dating-app/pkg/jwt"
-	"github.com/labstack/echo"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ghaniswara/dating-app/pkg/mail"
+	"github.com/ghaniswara/dating-app/pkg/oidc"
+	"github.com/ghaniswara/dating-app/pkg/sms"
+	"gorm.io/gorm"
+)
+
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
 )
 
 type IAuthUseCase interface {
 	SignupUser(ctx context.Context, request entity.CreateUserRequest) (*entity.User, error)
-	SignIn(ctx context.Context, email, username, password string) (string, error)
-	GetUserFromJWTRequest(c echo.Context) (*entity.User, error)
+
+	// Failed attempts are throttled per account and IP, wrong credentials and
+	// lockouts return a *SignInError. Users with two-factor authentication get
+	// a challenge token for CompleteMFAChallenge instead of the tokens
+	SignIn(ctx context.Context, email, username, password, ip string) (*entity.SignInResponse, error)
+
+	// Exchange a refresh token for a new access and refresh token, the old
+	// refresh token can't be used again
+	Refresh(ctx context.Context, refreshToken string) (*entity.SignInResponse, error)
+
+	// Revoke the access token of the request and, when given, the refresh
+	// token family it was signed in with
+	Logout(ctx context.Context, refreshToken string) error
+
+	// Revoke every access and refresh token of the user, on all devices
+	LogoutAll(ctx context.Context) error
+	IsTokenRevoked(ctx context.Context, userID int, tokenID string, issuedAt time.Time) (bool, error)
+
+	// Verify the signature and expiry of an access token
+	ParseAccessToken(accessToken string) (*jwt.Claims, error)
+
+	// Attach the claims to the context for ClaimsFromContext and UserFromContext
+	WithClaims(ctx context.Context, claims *jwt.Claims) context.Context
+
+	// Verify the email with the token sent on sign-up
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, userID int) error
+
+	// Email a reset link when the email belongs to a user, the outcome is the
+	// same either way so the caller can't tell whether the email exists
+	ForgotPassword(ctx context.Context, email string, ip string) error
+
+	// Set a new password with a reset token and sign out every session
+	ResetPassword(ctx context.Context, token string, password string, ip string) error
+
+	// Change the password of the user in the context and sign out every session
+	ChangePassword(ctx context.Context, currentPassword string, newPassword string) error
+
+	// Change the email of the user in the context, the new email has to be verified again
+	ChangeEmail(ctx context.Context, password string, email string) error
+
+	// Start enrolling the user in the context in TOTP, replacing a pending enrollment
+	EnrollTOTP(ctx context.Context) (*entity.TOTPEnrollmentResponse, error)
+
+	// Enable TOTP with a first code, returns the recovery codes
+	ConfirmTOTP(ctx context.Context, code string) (*entity.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, code string) error
+
+	// Exchange the challenge token from SignIn and a TOTP or recovery code for the tokens
+	CompleteMFAChallenge(ctx context.Context, challengeToken string, code string) (*entity.SignInResponse, error)
+
+	// URL of the provider's login page, it redirects back to CompleteOIDC
+	StartOIDC(ctx context.Context, provider string, loginHint string) (string, error)
+
+	// Sign in with the provider's authorization code, linking the identity to
+	// a user on first use. Ends like SignIn, with the tokens or an MFA challenge
+	CompleteOIDC(ctx context.Context, provider string, state string, code string) (*entity.SignInResponse, error)
+
+	// Text a one-time code to the phone, sends are limited per phone and IP
+	SendPhoneOTP(ctx context.Context, phone string, ip string) error
+
+	// Sign in with the code sent to the phone, creating a user with the given
+	// name on first use. Ends like SignIn, with the tokens or an MFA challenge
+	SignInWithPhone(ctx context.Context, phone string, code string, name string) (*entity.SignInResponse, error)
 }
 
 type authUseCase struct {
-	userRepo userRepo.IUserRepo
+	userRepo     userRepo.IUserRepo
+	tokenRepo    tokenRepo.ITokenRepo
+	securityRepo securityRepo.ISecurityRepo
+	mfaRepo      mfaRepo.IMFARepo
+	authRepo     authRepo.IAuthRepo
+	tokens       *jwt.Manager
+	mailer       mail.Mailer
+	sms          sms.Sender
+
+	// Base URL of the app, links in emails point to it
+	appURL string
+
+	// OpenID providers by the name used in the routes
+	oidcProviders map[string]*oidc.Provider
 }
 
-func New(userRepo userRepo.IUserRepo) IAuthUseCase {
+func New(
+	userRepo userRepo.IUserRepo,
+	tokenRepo tokenRepo.ITokenRepo,
+	securityRepo securityRepo.ISecurityRepo,
+	mfaRepo mfaRepo.IMFARepo,
+	authRepo authRepo.IAuthRepo,
+	tokens *jwt.Manager,
+	mailer mail.Mailer,
+	sms sms.Sender,
+	appURL string,
+	oidcProviders map[string]*oidc.Provider,
+) IAuthUseCase {
 	return &authUseCase{
-		userRepo: userRepo,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		securityRepo:  securityRepo,
+		mfaRepo:       mfaRepo,
+		authRepo:      authRepo,
+		tokens:        tokens,
+		mailer:        mailer,
+		sms:           sms,
+		appURL:        appURL,
+		oidcProviders: oidcProviders,
 	}
 }
 
 func (p *authUseCase) SignupUser(ctx context.Context, authData entity.CreateUserRequest) (*entity.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(authData.Password+authData.Email), 12)
+	password, err := hashPassword(authData.Password)
 	if err != nil {
 		return nil, err
 	}
-	authData.Password = string(hashedPassword)
 
-	user := entity.User{
+	user := &entity.User{
 		Name:      authData.Name,
-		Email:     authData.Email,
+		Email:     normalizeEmail(authData.Email),
 		Username:  authData.Username,
-		Password:  authData.Password,
 		IsPremium: false,
 	}
 
-	return p.userRepo.CreateUser(ctx, &user)
+	if err := p.authRepo.CreateUserWithPassword(ctx, user, password); err != nil {
+		return nil, err
+	}
+
+	// The user can ask for another email, a mail failure shouldn't fail sign-up
+	if err := p.sendVerificationEmail(ctx, user); err != nil {
+		log.Println("error sending verification email", user.ID, err)
+	}
+
+	return user, nil
 }
 
-func (p *authUseCase) SignIn(ctx context.Context, email, username, password string) (string, error) {
+func (p *authUseCase) SignIn(ctx context.Context, email, username, password, ip string) (*entity.SignInResponse, error) {
+	email = normalizeEmail(email)
+
 	user, err := p.userRepo.GetUserByUnameOrEmail(ctx, email, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	attempt := newSignInAttempt(user, email, username, ip)
+
+	if err := p.checkSignInLock(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, p.signInFailed(ctx, attempt)
+	}
+
+	credential, err := p.checkPassword(ctx, user, password)
+	if errors.Is(err, ErrInvalidPassword) {
+		return nil, p.signInFailed(ctx, attempt)
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password+user.Email)); err != nil {
-		return "", err
+	for _, key := range []string{attempt.accountKey, attempt.accountClientKey} {
+		if err := p.securityRepo.ClearFailures(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	// Checked after the password so the account status isn't revealed to anyone else
+	if err := checkSignInStatus(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// The plain password is only known here, a failed upgrade is retried on the next sign-in
+	if needsRehash(credential) {
+		if err := p.updatePassword(ctx, user, password); err != nil {
+			log.Println("error rehashing password", user.ID, err)
+		}
+	}
+
+	if err := p.authRepo.MarkCredentialUsed(ctx, int(user.ID), time.Now()); err != nil {
+		log.Println("error marking credential used", user.ID, err)
 	}
 
-	token, err := jwt.CreateToken(int(user.ID), user.Username)
+	return p.completeSignIn(ctx, user)
+}
+
+// Users with two-factor authentication get a challenge, others the tokens
+func (p *authUseCase) completeSignIn(ctx context.Context, user *entity.User) (*entity.SignInResponse, error) {
+	hasMFA, err := p.hasMFA(ctx, int(user.ID))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if hasMFA {
+		return p.newMFAChallenge(ctx, user)
+	}
+
+	return p.startSession(ctx, user)
+}
+
+// Issue the tokens of a new sign-in, its refresh tokens form a new family
+func (p *authUseCase) startSession(ctx context.Context, user *entity.User) (*entity.SignInResponse, error) {
+	familyID, err := newRandomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, stored, err := newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	restored := user.IsPendingDeletionAt(time.Now())
+	if restored {
+		if err := p.userRepo.UpdateAccountStatus(ctx, int(user.ID), entity.AccountActive, nil, "deletion cancelled by signing in", &user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := p.newSignInResponse(user, refreshToken)
+	if err != nil {
+		return nil, err
 	}
-	return token, nil
+
+	response.AccountRestored = restored
+
+	return response, nil
 }
 
-func (p *authUseCase) GetUserFromJWTRequest(c echo.Context) (*entity.User, error) {
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return nil, c.JSON(http.StatusUnauthorized, map[string]string{"message": "missing token"})
+// Like accountUseCase.CheckStatus, except that a deleted account can sign in
+// until it's erased, which restores it
+func checkSignInStatus(user *entity.User, now time.Time) error {
+	if user.IsPendingDeletionAt(now) {
+		return nil
 	}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return nil, c.JSON(http.StatusUnauthorized, map[string]string{"message": "invalid token format"})
+	return accountUseCase.CheckStatus(user, now)
+}
+
+func (p *authUseCase) Refresh(ctx context.Context, refreshToken string) (*entity.SignInResponse, error) {
+	current, err := p.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
 	}
-	token := parts[1]
 
-	claims, err := jwt.ValidateToken(token)
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		return nil, p.revokeReusedFamily(ctx, current.FamilyID)
+	}
 
+	if !time.Now().Before(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := p.userRepo.GetUserByID(ctx, int(current.UserID))
 	if err != nil {
-		return nil, c.JSON(http.StatusUnauthorized, map[string]string{"message": "invalid token"})
+		return nil, err
+	}
+
+	if err := accountUseCase.CheckStatus(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	nextToken, next, err := newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, err
 	}
 
-	return p.userRepo.GetUserByID(c.Request().Context(), claims.UserID)
+	rotated, err := p.tokenRepo.RotateRefreshToken(ctx, current.ID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request rotated the same token first
+	if !rotated {
+		return nil, p.revokeReusedFamily(ctx, current.FamilyID)
+	}
+
+	return p.newSignInResponse(user, nextToken)
+}
+
+func (p *authUseCase) Logout(ctx context.Context, refreshToken string) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	if refreshToken != "" {
+		current, err := p.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// A refresh token of another user is ignored rather than revoked
+		if err == nil && int(current.UserID) == claims.UserID {
+			if err := p.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.tokenRepo.RevokeAccessToken(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+}
+
+func (p *authUseCase) LogoutAll(ctx context.Context) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	return p.revokeAllSessions(ctx, claims.UserID)
+}
+
+func (p *authUseCase) IsTokenRevoked(ctx context.Context, userID int, tokenID string, issuedAt time.Time) (bool, error) {
+	revoked, err := p.tokenRepo.IsAccessTokenRevoked(ctx, tokenID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revokedAt, err := p.tokenRepo.GetUserAccessTokensRevokedAt(ctx, userID)
+	if err != nil || revokedAt == nil {
+		return false, err
+	}
+
+	// The marker has second precision like iat, tokens issued in the same
+	// second as the logout are revoked as well
+	return !issuedAt.After(*revokedAt), nil
+}
+
+func (p *authUseCase) ParseAccessToken(accessToken string) (*jwt.Claims, error) {
+	return p.tokens.ValidateToken(accessToken)
+}
+
+func (p *authUseCase) revokeAllSessions(ctx context.Context, userID int) error {
+	if err := p.tokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	// Access tokens issued before now expire within AccessTokenTTL, the marker
+	// only has to outlive them
+	return p.tokenRepo.RevokeUserAccessTokens(ctx, userID, time.Now(), jwt.AccessTokenTTL)
+}
+
+// A refresh token presented twice means it leaked, every token descending
+// from the same sign-in is revoked so both holders have to sign in again
+func (p *authUseCase) revokeReusedFamily(ctx context.Context, familyID string) error {
+	if err := p.tokenRepo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// Helper
+
+func (p *authUseCase) newSignInResponse(user *entity.User, refreshToken string) (*entity.SignInResponse, error) {
+	accessToken, err := p.tokens.CreateToken(int(user.ID), user.Username, string(user.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.SignInResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(jwt.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// Returns the token for the client and the hashed record to store
+func newRefreshToken(userID uint, familyID string) (string, *entity.RefreshToken, error) {
+	token, err := newRandomString(32, encodeToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &entity.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, nil
+}
+
+func newRandomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encode(b), nil
+}
+
+func encodeToken(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
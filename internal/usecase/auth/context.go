@@ -0,0 +1,57 @@
+package authUseCase
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/jwt"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated request")
+
+type contextKey struct{}
+
+// Authentication of a request, the user is loaded on first access
+type requestAuth struct {
+	claims *jwt.Claims
+	load   func() (*entity.User, error)
+
+	once sync.Once
+	user *entity.User
+	err  error
+}
+
+// Store the verified claims in the context, done once by JWTMiddleware
+func (p *authUseCase) WithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestAuth{
+		claims: claims,
+		load: func() (*entity.User, error) {
+			return p.userRepo.GetUserByID(ctx, claims.UserID)
+		},
+	})
+}
+
+func ClaimsFromContext(ctx context.Context) (*jwt.Claims, bool) {
+	auth, ok := ctx.Value(contextKey{}).(*requestAuth)
+	if !ok {
+		return nil, false
+	}
+
+	return auth.claims, true
+}
+
+// The authenticated user, loaded at most once per request
+func UserFromContext(ctx context.Context) (*entity.User, error) {
+	auth, ok := ctx.Value(contextKey{}).(*requestAuth)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	auth.once.Do(func() {
+		auth.user, auth.err = auth.load()
+	})
+
+	return auth.user, auth.err
+}
@@ -0,0 +1,303 @@
+package authUseCase
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer = "Dating App"
+
+	// Steps of clock drift allowed either way
+	totpSkew = 1
+
+	mfaChallengeTTL = 5 * time.Minute
+
+	// Codes tried on one challenge before it's dropped, and by a signed in
+	// user confirming or disabling TOTP within mfaChallengeTTL
+	mfaChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid code")
+	ErrInvalidChallenge  = errors.New("invalid challenge token")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (p *authUseCase) EnrollTOTP(ctx context.Context) (*entity.TOTPEnrollmentResponse, error) {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := p.mfaRepo.GetTOTPCredential(ctx, int(user.ID))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && credential.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.mfaRepo.SaveTOTPCredential(ctx, &entity.TOTPCredential{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TOTPEnrollmentResponse{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+func (p *authUseCase) ConfirmTOTP(ctx context.Context, code string) (*entity.RecoveryCodesResponse, error) {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := p.mfaRepo.GetTOTPCredential(ctx, int(user.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := p.limitMFAAttempts(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(credential.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashed, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enrolled again in the meantime
+	err = p.mfaRepo.EnableTOTP(ctx, int(user.ID), step, hashed)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (p *authUseCase) DisableTOTP(ctx context.Context, code string) error {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	credential, err := p.mfaRepo.GetTOTPCredential(ctx, int(user.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+
+	if credential.EnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	if err := p.limitMFAAttempts(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := p.verifyMFACode(ctx, credential, code); err != nil {
+		return err
+	}
+
+	return p.mfaRepo.DeleteTOTP(ctx, int(user.ID))
+}
+
+func (p *authUseCase) CompleteMFAChallenge(ctx context.Context, challengeToken string, code string) (*entity.SignInResponse, error) {
+	tokenHash := hashToken(challengeToken)
+
+	userID, err := p.mfaRepo.GetChallenge(ctx, tokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := p.tokenRepo.CountAttempt(ctx, ":auth:mfa-challenge:"+tokenHash+":attempts", mfaChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guessing codes needs a new challenge, so the password again
+	if attempts > mfaChallengeAttempts {
+		if _, err := p.mfaRepo.DeleteChallenge(ctx, tokenHash); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidChallenge
+	}
+
+	// New challenges only take a password, the user's own limit holds across them
+	if err := p.limitMFAAttempts(ctx, uint(userID)); err != nil {
+		return nil, err
+	}
+
+	user, err := p.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSignInStatus(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	credential, err := p.mfaRepo.GetTOTPCredential(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.verifyMFACode(ctx, credential, code); err != nil {
+		return nil, err
+	}
+
+	// Another request completed the challenge first
+	deleted, err := p.mfaRepo.DeleteChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidChallenge
+	}
+
+	return p.startSession(ctx, user)
+}
+
+// Sign-in stops here for users with two-factor authentication, the challenge
+// token proves the password was right
+func (p *authUseCase) newMFAChallenge(ctx context.Context, user *entity.User) (*entity.SignInResponse, error) {
+	token, err := newRandomString(32, encodeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.mfaRepo.CreateChallenge(ctx, hashToken(token), int(user.ID), mfaChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &entity.SignInResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresIn:      int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+func (p *authUseCase) hasMFA(ctx context.Context, userID int) (bool, error) {
+	credential, err := p.mfaRepo.GetTOTPCredential(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return credential.EnabledAt != nil, nil
+}
+
+// Codes checked for the user across challenges and sessions, a stolen
+// password or session could otherwise guess codes by starting over
+func (p *authUseCase) limitMFAAttempts(ctx context.Context, userID uint) error {
+	attempts, err := p.tokenRepo.CountAttempt(ctx, ":user:"+strconv.Itoa(int(userID))+":mfa:attempts", mfaChallengeTTL)
+	if err != nil {
+		return err
+	}
+
+	if attempts > mfaChallengeAttempts {
+		return ErrTooManyRequests
+	}
+
+	return nil
+}
+
+// Accept a TOTP code or an unused recovery code, each works once
+func (p *authUseCase) verifyMFACode(ctx context.Context, credential *entity.TOTPCredential, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(credential.Secret, code, time.Now(), totpSkew); ok {
+		used, err := p.mfaRepo.UseTOTPStep(ctx, int(credential.UserID), step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := p.mfaRepo.UseRecoveryCode(ctx, int(credential.UserID), hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// Returns the codes for the user, formatted as xxxxx-xxxxx, and the hashed
+// records to store
+func newRecoveryCodes(userID uint) ([]string, []entity.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashed := make([]entity.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRandomString(7, func(b []byte) string {
+			return strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashed = append(hashed, entity.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(code),
+		})
+	}
+
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,184 @@
+package authUseCase
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/oidc"
+	"gorm.io/gorm"
+)
+
+// Time the user has to sign in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider   = errors.New("unknown provider")
+	ErrInvalidOIDCState  = errors.New("invalid state")
+	ErrOIDCFailed        = errors.New("sign-in with the provider failed")
+	ErrOIDCEmailRequired = errors.New("provider didn't share an email")
+
+	// The provider's email belongs to a user but the provider didn't verify it
+	ErrOIDCEmailInUse = errors.New("email belongs to another account")
+)
+
+func (p *authUseCase) StartOIDC(ctx context.Context, providerName string, loginHint string) (string, error) {
+	provider, ok := p.oidcProviders[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	err = p.authRepo.SaveOIDCState(ctx, state, entity.OIDCState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, oidcStateTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier), loginHint)
+}
+
+func (p *authUseCase) CompleteOIDC(ctx context.Context, providerName string, state string, code string) (*entity.SignInResponse, error) {
+	provider, ok := p.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	saved, err := p.authRepo.ConsumeOIDCState(ctx, state)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.Provider != providerName {
+		return nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, saved.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCFailed, err)
+	}
+
+	idToken, err := provider.VerifyIDToken(ctx, rawIDToken, saved.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCFailed, err)
+	}
+
+	user, err := p.oidcUser(ctx, providerName, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSignInStatus(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return p.completeSignIn(ctx, user)
+}
+
+// The user who linked the identity, else the user with the same email when
+// both the provider and the user verified it, else a new user
+func (p *authUseCase) oidcUser(ctx context.Context, providerName string, idToken *oidc.IDToken) (*entity.User, error) {
+	identity, err := p.authRepo.GetIdentity(ctx, providerName, idToken.Subject)
+	if err == nil {
+		return p.userRepo.GetUserByID(ctx, int(identity.UserID))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if idToken.Email == "" {
+		return nil, ErrOIDCEmailRequired
+	}
+	idToken.Email = normalizeEmail(idToken.Email)
+
+	identity = &entity.Identity{
+		Provider: providerName,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+	}
+
+	existing, err := p.userRepo.GetUserByUnameOrEmail(ctx, idToken.Email, "")
+	if err == nil {
+		// Linking on an unverified email would let anyone claim the account.
+		// The account's own email has to be verified too, else whoever signed
+		// up with someone else's email first would keep a password on the
+		// account the real owner then signs in to
+		if !idToken.EmailVerified || existing.EmailVerifiedAt == nil {
+			return nil, ErrOIDCEmailInUse
+		}
+
+		identity.UserID = existing.ID
+		if err := p.authRepo.CreateIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return p.createOIDCUser(ctx, idToken, identity)
+}
+
+// Users signing up with a provider have no password, they can set one with
+// a password reset
+func (p *authUseCase) createOIDCUser(ctx context.Context, idToken *oidc.IDToken, identity *entity.Identity) (*entity.User, error) {
+	username, err := newRandomString(6, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	name := idToken.Name
+	if name == "" {
+		name, _, _ = strings.Cut(idToken.Email, "@")
+	}
+
+	user := &entity.User{
+		Name:     name,
+		Email:    idToken.Email,
+		Username: "user" + username,
+	}
+
+	if idToken.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := p.authRepo.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := p.sendVerificationEmail(ctx, user); err != nil {
+			log.Println("error sending verification email", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,277 @@
+package authUseCase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/mail"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTokenTTL = time.Hour
+
+	// Forgot requests allowed per email and per IP in the window, reset
+	// attempts share the IP limit
+	passwordResetEmailLimit = 3
+	passwordResetIPLimit    = 10
+	passwordResetWindow     = time.Hour
+
+	// The reset mail is sent after responding, bounded by this
+	passwordResetSendTimeout = 30 * time.Second
+
+	// Wrong current passwords allowed in the window when changing the email or
+	// password, the check is then locked for the window
+	passwordCheckLimit  = 5
+	passwordCheckWindow = 15 * time.Minute
+)
+
+// Parameters of new password hashes, a stored hash made with other ones is
+// replaced on the next sign-in
+const (
+	passwordAlgorithm = entity.PasswordBcrypt
+	passwordCost      = 12
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid reset token")
+	ErrInvalidPassword   = errors.New("invalid password")
+)
+
+func (p *authUseCase) ForgotPassword(ctx context.Context, email string, ip string) error {
+	email = normalizeEmail(email)
+
+	// Limited before the lookup so unknown emails are throttled the same way
+	if err := p.checkPasswordResetLimit(ctx, "ip:"+ip, passwordResetIPLimit); err != nil {
+		return err
+	}
+
+	emailHash := sha256.Sum256([]byte(email))
+	if err := p.checkPasswordResetLimit(ctx, "email:"+hex.EncodeToString(emailHash[:]), passwordResetEmailLimit); err != nil {
+		return err
+	}
+
+	// The rest happens after responding, known and unknown emails would
+	// otherwise be told apart by how long the request takes
+	go p.sendPasswordReset(context.WithoutCancel(ctx), email)
+
+	return nil
+}
+
+func (p *authUseCase) sendPasswordReset(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(ctx, passwordResetSendTimeout)
+	defer cancel()
+
+	user, err := p.userRepo.GetUserByUnameOrEmail(ctx, email, "")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		log.Println("error looking up password reset user", err)
+		return
+	}
+
+	// Only the latest link works
+	if err := p.tokenRepo.InvalidateOneTimeTokens(ctx, int(user.ID), entity.TokenPasswordReset); err != nil {
+		log.Println("error invalidating password reset tokens", user.ID, err)
+		return
+	}
+
+	token, err := newRandomString(32, encodeToken)
+	if err != nil {
+		log.Println("error creating password reset token", user.ID, err)
+		return
+	}
+
+	err = p.tokenRepo.CreateOneTimeToken(ctx, &entity.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   entity.TokenPasswordReset,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	})
+	if err != nil {
+		log.Println("error storing password reset token", user.ID, err)
+		return
+	}
+
+	link := p.appURL + "/reset-password?token=" + url.QueryEscape(token)
+
+	err = p.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. Open this link within an hour to choose a new password:\n%s\n\nIf it wasn't you, you can ignore this email.\n", user.Name, link),
+	})
+	if err != nil {
+		log.Println("error sending password reset email", user.ID, err)
+	}
+}
+
+func (p *authUseCase) ResetPassword(ctx context.Context, token string, password string, ip string) error {
+	if err := p.checkPasswordResetLimit(ctx, "ip:"+ip, passwordResetIPLimit); err != nil {
+		return err
+	}
+
+	reset, err := p.tokenRepo.ConsumeOneTimeToken(ctx, entity.TokenPasswordReset, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := p.userRepo.GetUserByID(ctx, int(reset.UserID))
+	if err != nil {
+		return err
+	}
+
+	// The link was sent to an email the user no longer has
+	if user.Email != reset.Email {
+		return ErrInvalidResetToken
+	}
+
+	if err := p.updatePassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	return p.revokeAllSessions(ctx, int(user.ID))
+}
+
+func (p *authUseCase) ChangePassword(ctx context.Context, currentPassword string, newPassword string) error {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.checkCurrentPassword(ctx, user, currentPassword); err != nil {
+		return err
+	}
+
+	if err := p.updatePassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	return p.revokeAllSessions(ctx, int(user.ID))
+}
+
+func (p *authUseCase) updatePassword(ctx context.Context, user *entity.User, password string) error {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return p.authRepo.SavePassword(ctx, int(user.ID), hashed)
+}
+
+// The credential when the password matches, ErrInvalidPassword when it
+// doesn't or the user has no password
+func (p *authUseCase) checkPassword(ctx context.Context, user *entity.User, password string) (*entity.AuthCredential, error) {
+	credential, err := p.authRepo.GetCredential(ctx, int(user.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyPassword(credential, user.Email, password); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	return credential, nil
+}
+
+// checkPassword for a signed-in user confirming a change, wrong passwords lock
+// the check for a while so a stolen token can't be used to guess the password
+func (p *authUseCase) checkCurrentPassword(ctx context.Context, user *entity.User, password string) (*entity.AuthCredential, error) {
+	key := ":user:" + strconv.Itoa(int(user.ID)) + ":password-check"
+
+	locked, err := p.securityRepo.GetLock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if locked > 0 {
+		return nil, ErrTooManyRequests
+	}
+
+	credential, err := p.checkPassword(ctx, user, password)
+	if !errors.Is(err, ErrInvalidPassword) {
+		return credential, err
+	}
+
+	failures, failErr := p.securityRepo.AddFailure(ctx, key, time.Now(), passwordCheckWindow)
+	if failErr != nil {
+		return nil, failErr
+	}
+
+	if failures >= passwordCheckLimit {
+		if err := p.securityRepo.Lock(ctx, key, passwordCheckWindow); err != nil {
+			return nil, err
+		}
+		if err := p.securityRepo.ClearFailures(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (p *authUseCase) checkPasswordResetLimit(ctx context.Context, key string, limit int64) error {
+	attempts, err := p.tokenRepo.CountAttempt(ctx, ":auth:password-reset:"+key, passwordResetWindow)
+	if err != nil {
+		return err
+	}
+
+	if attempts > limit {
+		return ErrTooManyRequests
+	}
+
+	return nil
+}
+
+// Emails are stored lowercase so the same address can't be taken twice
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func hashPassword(password string) (entity.PasswordHash, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return entity.PasswordHash{}, err
+	}
+
+	return entity.PasswordHash{
+		Hash:      string(hashed),
+		Algorithm: passwordAlgorithm,
+		Cost:      passwordCost,
+	}, nil
+}
+
+func verifyPassword(credential *entity.AuthCredential, email string, password string) error {
+	switch credential.PasswordAlgorithm {
+	case entity.PasswordBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(password))
+	case entity.PasswordBcryptEmail:
+		// Salted with the email as it was before emails were lowercased
+		if credential.PasswordSalt != "" {
+			email = credential.PasswordSalt
+		}
+		return bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(password+email))
+	default:
+		return fmt.Errorf("unknown password algorithm %q", credential.PasswordAlgorithm)
+	}
+}
+
+func needsRehash(credential *entity.AuthCredential) bool {
+	return credential.PasswordAlgorithm != passwordAlgorithm || credential.PasswordCost != passwordCost
+}
@@ -0,0 +1,171 @@
+package authUseCase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/sms"
+	"gorm.io/gorm"
+)
+
+const (
+	phoneOTPDigits = 6
+	phoneOTPTTL    = 5 * time.Minute
+
+	// Wrong codes before the code is dropped and a new one has to be sent
+	phoneOTPAttempts = 5
+
+	phoneOTPWindow     = time.Hour
+	phoneOTPPhoneLimit = 5
+	phoneOTPIPLimit    = 20
+)
+
+var ErrInvalidPhoneOTP = errors.New("invalid or expired code")
+
+func (p *authUseCase) SendPhoneOTP(ctx context.Context, phone string, ip string) error {
+	phoneHash := hashToken(phone)
+
+	if err := p.checkPhoneOTPLimit(ctx, "ip:"+ip, phoneOTPIPLimit); err != nil {
+		return err
+	}
+
+	if err := p.checkPhoneOTPLimit(ctx, "phone:"+phoneHash, phoneOTPPhoneLimit); err != nil {
+		return err
+	}
+
+	code, err := newPhoneOTP()
+	if err != nil {
+		return err
+	}
+
+	// Only the latest code works
+	if err := p.authRepo.SavePhoneOTP(ctx, phoneHash, hashPhoneOTP(phone, code), phoneOTPTTL); err != nil {
+		return err
+	}
+
+	return p.sms.Send(ctx, sms.Message{
+		To:   phone,
+		Body: fmt.Sprintf("Your sign-in code is %s. It expires in %d minutes, don't share it with anyone.", code, int(phoneOTPTTL.Minutes())),
+	})
+}
+
+func (p *authUseCase) SignInWithPhone(ctx context.Context, phone string, code string, name string) (*entity.SignInResponse, error) {
+	phoneHash := hashToken(phone)
+
+	codeHash, err := p.authRepo.GetPhoneOTP(ctx, phoneHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPhoneOTP
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := p.authRepo.CountPhoneOTPAttempt(ctx, phoneHash, phoneOTPTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guessing further needs a new code, which is limited per phone
+	if attempts > phoneOTPAttempts {
+		if _, err := p.authRepo.DeletePhoneOTP(ctx, phoneHash); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidPhoneOTP
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashPhoneOTP(phone, code))) != 1 {
+		return nil, ErrInvalidPhoneOTP
+	}
+
+	// Another request used the code first
+	deleted, err := p.authRepo.DeletePhoneOTP(ctx, phoneHash)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidPhoneOTP
+	}
+
+	user, err := p.phoneUser(ctx, phone, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSignInStatus(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return p.completeSignIn(ctx, user)
+}
+
+// The user with the phone, else a new user. The phone is verified by the code
+// so it counts like a verified email for dating profiles
+func (p *authUseCase) phoneUser(ctx context.Context, phone string, name string) (*entity.User, error) {
+	user, err := p.userRepo.GetUserByPhone(ctx, phone)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	username, err := newRandomString(6, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = "user" + username
+	}
+
+	now := time.Now()
+
+	user = &entity.User{
+		Name:            name,
+		Username:        "user" + username,
+		Phone:           phone,
+		PhoneVerifiedAt: &now,
+	}
+
+	if err := p.authRepo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (p *authUseCase) checkPhoneOTPLimit(ctx context.Context, key string, limit int64) error {
+	attempts, err := p.tokenRepo.CountAttempt(ctx, ":auth:phone-otp:limit:"+key, phoneOTPWindow)
+	if err != nil {
+		return err
+	}
+
+	if attempts > limit {
+		return ErrTooManyRequests
+	}
+
+	return nil
+}
+
+func newPhoneOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < phoneOTPDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", phoneOTPDigits, n), nil
+}
+
+// Salted with the phone so equal codes of different phones don't share a hash
+func hashPhoneOTP(phone string, code string) string {
+	return hashToken(phone + ":" + code)
+}
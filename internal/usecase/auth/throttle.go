@@ -0,0 +1,202 @@
+package authUseCase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+)
+
+const (
+	signInFailureWindow = 15 * time.Minute
+
+	// Failures in the window before the account is locked for the client, or
+	// the IP for everyone. An IP is shared by more people so it gets more
+	signInAccountLimit = 5
+	signInIPLimit      = 20
+
+	// Failures in the window before clients should show a CAPTCHA, account
+	// failures from every client count
+	signInAccountCaptchaLimit = 3
+	signInIPCaptchaLimit      = 10
+
+	// Each lockout in the lockout window doubles the previous one
+	signInLockoutBase   = time.Minute
+	signInLockoutMax    = time.Hour
+	signInLockoutWindow = 24 * time.Hour
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrSignInLocked       = errors.New("too many failed sign-ins")
+)
+
+// Returned by SignIn for wrong credentials and locked sign-ins, wraps
+// ErrInvalidCredentials or ErrSignInLocked
+type SignInError struct {
+	Err             error
+	CaptchaRequired bool
+	RetryAfter      time.Duration
+}
+
+func (e *SignInError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SignInError) Unwrap() error {
+	return e.Err
+}
+
+// Throttling keys of a sign-in, unknown identifiers are throttled like
+// accounts so a lockout doesn't tell whether the account exists.
+//
+// The account is only locked for the client guessing its password, otherwise
+// anyone could keep a user out of their account by failing on purpose. Failures
+// from every client still add up to ask for a CAPTCHA
+type signInAttempt struct {
+	user             *entity.User
+	identifier       string
+	ip               string
+	accountKey       string
+	accountClientKey string
+	ipKey            string
+}
+
+func newSignInAttempt(user *entity.User, email, username, ip string) signInAttempt {
+	identifier := email
+	if identifier == "" {
+		identifier = username
+	}
+
+	attempt := signInAttempt{
+		user:       user,
+		identifier: identifier,
+		ip:         ip,
+		ipKey:      ":auth:sign-in:ip:" + ip,
+	}
+
+	if user != nil {
+		attempt.accountKey = ":user:" + strconv.Itoa(int(user.ID)) + ":sign-in"
+	} else {
+		sum := sha256.Sum256([]byte(strings.ToLower(identifier)))
+		attempt.accountKey = ":auth:sign-in:unknown:" + hex.EncodeToString(sum[:])
+	}
+
+	attempt.accountClientKey = attempt.accountKey + ":ip:" + ip
+
+	return attempt
+}
+
+func (p *authUseCase) checkSignInLock(ctx context.Context, attempt signInAttempt) error {
+	var retryAfter time.Duration
+
+	for _, key := range []string{attempt.accountClientKey, attempt.ipKey} {
+		ttl, err := p.securityRepo.GetLock(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	if retryAfter > 0 {
+		return &SignInError{Err: ErrSignInLocked, CaptchaRequired: true, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// Record the failure and lock the account or IP once it has too many
+func (p *authUseCase) signInFailed(ctx context.Context, attempt signInAttempt) error {
+	now := time.Now()
+	p.auditSignIn(ctx, attempt, entity.SecurityEventSignInFailed)
+
+	accountFailures, err := p.securityRepo.AddFailure(ctx, attempt.accountKey, now, signInFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	clientFailures, err := p.securityRepo.AddFailure(ctx, attempt.accountClientKey, now, signInFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	ipFailures, err := p.securityRepo.AddFailure(ctx, attempt.ipKey, now, signInFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+
+	if clientFailures >= signInAccountLimit {
+		if retryAfter, err = p.lockSignIn(ctx, attempt.accountClientKey); err != nil {
+			return err
+		}
+	}
+
+	if ipFailures >= signInIPLimit {
+		ipRetryAfter, err := p.lockSignIn(ctx, attempt.ipKey)
+		if err != nil {
+			return err
+		}
+
+		if ipRetryAfter > retryAfter {
+			retryAfter = ipRetryAfter
+		}
+	}
+
+	if retryAfter > 0 {
+		p.auditSignIn(ctx, attempt, entity.SecurityEventSignInLocked)
+		return &SignInError{Err: ErrSignInLocked, CaptchaRequired: true, RetryAfter: retryAfter}
+	}
+
+	return &SignInError{
+		Err:             ErrInvalidCredentials,
+		CaptchaRequired: accountFailures >= signInAccountCaptchaLimit || ipFailures >= signInIPCaptchaLimit,
+	}
+}
+
+// The failures are cleared with the lock, once it's over the key gets the
+// full limit again before a longer lockout
+func (p *authUseCase) lockSignIn(ctx context.Context, key string) (time.Duration, error) {
+	lockouts, err := p.securityRepo.CountLockout(ctx, key, signInLockoutWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl := signInLockoutMax
+	if lockouts <= 6 {
+		ttl = min(signInLockoutBase<<(lockouts-1), signInLockoutMax)
+	}
+
+	if err := p.securityRepo.Lock(ctx, key, ttl); err != nil {
+		return 0, err
+	}
+
+	return ttl, p.securityRepo.ClearFailures(ctx, key)
+}
+
+// The audit log is best effort, failing to write it doesn't fail the sign-in
+func (p *authUseCase) auditSignIn(ctx context.Context, attempt signInAttempt, event entity.SecurityEvent) {
+	entry := &entity.SecurityAuditLog{
+		Event:      event,
+		Identifier: attempt.identifier,
+		IP:         attempt.ip,
+	}
+
+	if attempt.user != nil {
+		entry.UserID = &attempt.user.ID
+	}
+
+	if err := p.securityRepo.CreateAuditLog(ctx, entry); err != nil {
+		log.Println("error writing security audit log", event, err)
+	}
+}
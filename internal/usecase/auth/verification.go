@@ -0,0 +1,154 @@
+package authUseCase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/mail"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationTokenTTL = 24 * time.Hour
+
+	// Resends allowed per user in the window
+	verificationResendLimit  = 3
+	verificationResendWindow = time.Hour
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
+	ErrTooManyRequests          = errors.New("too many requests")
+	ErrEmailTaken               = errors.New("email taken")
+	ErrNoEmail                  = errors.New("account has no email")
+)
+
+func (p *authUseCase) VerifyEmail(ctx context.Context, token string) error {
+	verification, err := p.tokenRepo.ConsumeOneTimeToken(ctx, entity.TokenEmailVerification, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fails when the email changed since the token was sent
+	err = p.userRepo.MarkEmailVerified(ctx, int(verification.UserID), verification.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidVerificationToken
+	}
+
+	return err
+}
+
+func (p *authUseCase) ChangeEmail(ctx context.Context, password string, email string) error {
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	credential, err := p.checkCurrentPassword(ctx, user, password)
+	if err != nil {
+		return err
+	}
+
+	email = normalizeEmail(email)
+
+	if email == user.Email {
+		return nil
+	}
+
+	taken, err := p.userRepo.IsEmailTaken(ctx, email, int(user.ID))
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	// A legacy hash is salted with the old email and would stop matching
+	if needsRehash(credential) {
+		if err := p.updatePassword(ctx, user, password); err != nil {
+			return err
+		}
+	}
+
+	err = p.userRepo.UpdateEmail(ctx, int(user.ID), email)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := *user
+	changed.Email = email
+	changed.EmailVerifiedAt = nil
+
+	// The user can ask for another email, a mail failure shouldn't fail the change
+	if err := p.sendVerificationEmail(ctx, &changed); err != nil {
+		log.Println("error sending verification email", user.ID, err)
+	}
+
+	return nil
+}
+
+func (p *authUseCase) ResendVerificationEmail(ctx context.Context, userID int) error {
+	user, err := p.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Signed up with a phone number
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	attempts, err := p.tokenRepo.CountAttempt(ctx, ":user:"+strconv.Itoa(userID)+":verify-email:resend", verificationResendWindow)
+	if err != nil {
+		return err
+	}
+
+	if attempts > verificationResendLimit {
+		return ErrTooManyRequests
+	}
+
+	return p.sendVerificationEmail(ctx, user)
+}
+
+func (p *authUseCase) sendVerificationEmail(ctx context.Context, user *entity.User) error {
+	token, err := newRandomString(32, encodeToken)
+	if err != nil {
+		return err
+	}
+
+	err = p.tokenRepo.CreateOneTimeToken(ctx, &entity.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   entity.TokenEmailVerification,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := p.appURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return p.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link within 24 hours:\n%s\n", user.Name, link),
+	})
+}
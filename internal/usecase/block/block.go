@@ -0,0 +1,64 @@
+package blockUseCase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	blockRepo "github.com/ghaniswara/dating-app/internal/repository/block"
+	matchRepo "github.com/ghaniswara/dating-app/internal/repository/match"
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBlockSelf    = errors.New("cannot block yourself")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type IBlockUseCase interface {
+	BlockUser(ctx context.Context, userID int, blockedID int) error
+	UnblockUser(ctx context.Context, userID int, blockedID int) error
+	GetBlockedUsers(ctx context.Context, userID int) ([]entity.Block, error)
+}
+
+type blockUseCase struct {
+	blockRepo blockRepo.IBlockRepo
+	matchRepo matchRepo.IMatchRepo
+	userRepo  userRepo.IUserRepo
+}
+
+func New(blockRepo blockRepo.IBlockRepo, matchRepo matchRepo.IMatchRepo, userRepo userRepo.IUserRepo) IBlockUseCase {
+	return &blockUseCase{
+		blockRepo: blockRepo,
+		matchRepo: matchRepo,
+		userRepo:  userRepo,
+	}
+}
+
+func (b *blockUseCase) BlockUser(ctx context.Context, userID int, blockedID int) error {
+	if userID == blockedID {
+		return ErrBlockSelf
+	}
+
+	if _, err := b.userRepo.GetUserByID(ctx, blockedID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if err := b.blockRepo.CreateBlock(ctx, userID, blockedID); err != nil {
+		return err
+	}
+
+	return b.matchRepo.EndMatch(ctx, userID, blockedID)
+}
+
+func (b *blockUseCase) UnblockUser(ctx context.Context, userID int, blockedID int) error {
+	return b.blockRepo.DeleteBlock(ctx, userID, blockedID)
+}
+
+func (b *blockUseCase) GetBlockedUsers(ctx context.Context, userID int) ([]entity.Block, error) {
+	return b.blockRepo.GetBlocks(ctx, userID)
+}
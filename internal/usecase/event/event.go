@@ -0,0 +1,45 @@
+package eventUseCase
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	eventRepo "github.com/ghaniswara/dating-app/internal/repository/event"
+)
+
+type IEventUseCase interface {
+	Publish(ctx context.Context, userID int, eventType entity.EventType, data interface{}) error
+
+	// Resolve the position to start streaming from, an empty lastEventID means
+	// the client only wants events published after it connected
+	GetCursor(ctx context.Context, userID int, lastEventID string) (string, error)
+	WaitForEvents(ctx context.Context, userID int, cursor string, timeout time.Duration) ([]entity.UserEvent, error)
+}
+
+type eventUseCase struct {
+	eventRepo eventRepo.IEventRepo
+}
+
+func New(eventRepo eventRepo.IEventRepo) IEventUseCase {
+	return &eventUseCase{
+		eventRepo: eventRepo,
+	}
+}
+
+func (e *eventUseCase) Publish(ctx context.Context, userID int, eventType entity.EventType, data interface{}) error {
+	_, err := e.eventRepo.AppendUserEvent(ctx, userID, eventType, data)
+	return err
+}
+
+func (e *eventUseCase) GetCursor(ctx context.Context, userID int, lastEventID string) (string, error) {
+	if lastEventID != "" {
+		return lastEventID, nil
+	}
+
+	return e.eventRepo.GetLatestUserEventID(ctx, userID)
+}
+
+func (e *eventUseCase) WaitForEvents(ctx context.Context, userID int, cursor string, timeout time.Duration) ([]entity.UserEvent, error) {
+	return e.eventRepo.ReadUserEvents(ctx, userID, cursor, timeout)
+}
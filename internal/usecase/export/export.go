@@ -0,0 +1,337 @@
+package exportUseCase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	exportRepo "github.com/ghaniswara/dating-app/internal/repository/export"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	"gorm.io/gorm"
+)
+
+const (
+	// Time a ready archive can be downloaded before it's dropped
+	ArchiveRetention = 7 * 24 * time.Hour
+
+	// Lifetime of a download link, a new one is made on every poll
+	DownloadLinkTTL = 15 * time.Minute
+
+	// Time before the user can request another export, unless the last one failed
+	RequestCooldown = 24 * time.Hour
+
+	maxBuildAttempts = 3
+
+	// Time a worker has to build a claimed export before another one can
+	// claim it
+	buildLease = 10 * time.Minute
+)
+
+var (
+	ErrExportNotFound      = errors.New("export not found")
+	ErrExportRequested     = errors.New("export requested recently")
+	ErrExportNotReady      = errors.New("export not ready")
+	ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+)
+
+type IExportUseCase interface {
+	// Queue an export of everything held about the user for the export worker
+	RequestExport(ctx context.Context, userID int) (*entity.DataExport, error)
+
+	// ErrExportNotFound when the export doesn't exist or isn't the user's
+	GetExport(ctx context.Context, userID int, exportID int) (*entity.DataExport, error)
+
+	// Signed link to download the archive, valid until expiresAt
+	DownloadURL(exportID int, expiresAt time.Time) string
+
+	// Archive of a signed download link
+	GetArchive(ctx context.Context, exportID int, expires string, signature string) ([]byte, error)
+
+	// Used by the export worker
+	BuildPending(ctx context.Context, limit int) (int, error)
+	ExpireArchives(ctx context.Context, now time.Time) (int64, error)
+}
+
+type exportUseCase struct {
+	exportRepo exportRepo.IExportRepo
+	photoCase  photoUseCase.IPhotoUseCase
+	signingKey []byte
+	appURL     string
+}
+
+func New(exportRepo exportRepo.IExportRepo, photoCase photoUseCase.IPhotoUseCase, signingKey string, appURL string) IExportUseCase {
+	return &exportUseCase{
+		exportRepo: exportRepo,
+		photoCase:  photoCase,
+		signingKey: []byte(signingKey),
+		appURL:     strings.TrimSuffix(appURL, "/"),
+	}
+}
+
+func (e *exportUseCase) RequestExport(ctx context.Context, userID int) (*entity.DataExport, error) {
+	latest, err := e.exportRepo.GetLatestExport(ctx, userID)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err == nil && latest.Status != entity.DataExportFailed && time.Since(latest.CreatedAt) < RequestCooldown {
+		return latest, ErrExportRequested
+	}
+
+	export := &entity.DataExport{
+		UserID: uint(userID),
+		Status: entity.DataExportPending,
+	}
+
+	if err := e.exportRepo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (e *exportUseCase) GetExport(ctx context.Context, userID int, exportID int) (*entity.DataExport, error) {
+	export, err := e.exportRepo.GetExport(ctx, exportID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && int(export.UserID) != userID) {
+		return nil, ErrExportNotFound
+	}
+
+	return export, err
+}
+
+func (e *exportUseCase) DownloadURL(exportID int, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return fmt.Sprintf("%s/v1/account/export/%d/download?expires=%s&signature=%s",
+		e.appURL, exportID, expires, e.sign(exportID, expires))
+}
+
+func (e *exportUseCase) GetArchive(ctx context.Context, exportID int, expires string, signature string) ([]byte, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidDownloadLink
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(e.sign(exportID, expires))) {
+		return nil, ErrInvalidDownloadLink
+	}
+
+	archive, err := e.exportRepo.GetArchive(ctx, exportID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotReady
+	}
+
+	return archive, err
+}
+
+// A failed build counts an attempt, the export fails once it reaches
+// maxBuildAttempts and is retried on a later run otherwise
+func (e *exportUseCase) BuildPending(ctx context.Context, limit int) (int, error) {
+	claimed, err := e.exportRepo.ClaimPending(ctx, limit, buildLease)
+	if err != nil {
+		return 0, err
+	}
+
+	built := 0
+
+	for _, export := range claimed {
+		archive, err := e.buildArchive(ctx, export)
+
+		if err != nil {
+			failed, failErr := e.exportRepo.FailExport(ctx, export, err, maxBuildAttempts)
+			if failErr != nil {
+				return built, failErr
+			}
+			if failed {
+				log.Println("data export failed", export.ID, err)
+			}
+			continue
+		}
+
+		completed, err := e.exportRepo.CompleteExport(ctx, export.ID, archive, time.Now().Add(ArchiveRetention))
+		if err != nil {
+			return built, err
+		}
+		if completed {
+			built++
+		}
+	}
+
+	return built, nil
+}
+
+func (e *exportUseCase) ExpireArchives(ctx context.Context, now time.Time) (int64, error) {
+	return e.exportRepo.ExpireArchives(ctx, now)
+}
+
+// Helper
+
+func (e *exportUseCase) sign(exportID int, expires string) string {
+	mac := hmac.New(sha256.New, e.signingKey)
+	mac.Write([]byte(strconv.Itoa(exportID) + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (e *exportUseCase) buildArchive(ctx context.Context, export entity.DataExport) ([]byte, error) {
+	data, err := e.exportRepo.GetPersonalData(ctx, int(export.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	e.photoCase.SetURLs(data.Photos)
+
+	return buildArchive(data)
+}
+
+// One JSON file per kind of data
+func buildArchive(data *entity.PersonalData) ([]byte, error) {
+	user := data.User
+
+	photos := []entity.PhotoResponse{}
+	for _, photo := range data.Photos {
+		photos = append(photos, entity.NewPhotoResponse(photo))
+	}
+
+	swipes := []entity.SwipeResponse{}
+	for _, swipe := range data.Swipes {
+		swipes = append(swipes, entity.SwipeResponse{
+			ID:        int(swipe.ID),
+			ToID:      int(swipe.ToID),
+			Action:    swipe.Action.String(),
+			IsMatched: swipe.IsMatched,
+			Time:      swipe.Time,
+		})
+	}
+
+	matches := []entity.ExportMatch{}
+	for _, match := range data.Matches {
+		matches = append(matches, entity.ExportMatch{UserID: int(match.UserID), Time: match.Time})
+	}
+
+	blocks := []entity.BlockResponse{}
+	for _, block := range data.Blocks {
+		blocks = append(blocks, entity.BlockResponse{UserID: int(block.BlockedID), CreatedAt: block.CreatedAt})
+	}
+
+	reports := []entity.ReportResponse{}
+	for _, report := range data.Reports {
+		// Who moderated the report isn't the reporter's data
+		response := entity.NewReportResponse(report)
+		response.ReviewerID = nil
+		reports = append(reports, response)
+	}
+
+	devices := []entity.DeviceResponse{}
+	for _, device := range data.Devices {
+		devices = append(devices, entity.DeviceResponse{ID: int(device.ID), Platform: device.Platform})
+	}
+
+	preference := entity.DefaultNotificationPreference(user.ID)
+	if data.NotificationPreference != nil {
+		preference = *data.NotificationPreference
+	}
+
+	identities := []entity.ExportIdentity{}
+	for _, identity := range data.Identities {
+		identities = append(identities, entity.ExportIdentity{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+
+	statusChanges := []entity.ExportStatusChange{}
+	for _, audit := range data.AccountStatusAudits {
+		statusChanges = append(statusChanges, entity.ExportStatusChange{
+			From:           audit.FromStatus.String(),
+			To:             audit.ToStatus.String(),
+			SuspendedUntil: audit.SuspendedUntil,
+			Reason:         audit.Reason,
+			CreatedAt:      audit.CreatedAt,
+		})
+	}
+
+	securityEvents := []entity.ExportSecurityEvent{}
+	for _, log := range data.SecurityAuditLogs {
+		securityEvents = append(securityEvents, entity.ExportSecurityEvent{
+			Event:      log.Event,
+			Identifier: log.Identifier,
+			IP:         log.IP,
+			CreatedAt:  log.CreatedAt,
+		})
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", entity.ExportProfile{
+			ID:                 int(user.ID),
+			Name:               user.Name,
+			Email:              user.Email,
+			Username:           user.Username,
+			Phone:              user.Phone,
+			Role:               user.Role,
+			Status:             user.AccountStatusAt(time.Now()).String(),
+			EmailVerifiedAt:    user.EmailVerifiedAt,
+			PhoneVerifiedAt:    user.PhoneVerifiedAt,
+			PasswordChangedAt:  data.PasswordChangedAt,
+			TwoFactorEnabledAt: data.TwoFactorEnabledAt,
+			CreatedAt:          user.CreatedAt,
+			UpdatedAt:          user.UpdatedAt,
+		}},
+		{"subscription.json", entity.ExportSubscription{IsPremium: user.IsPremium}},
+		{"photos.json", photos},
+		{"swipes.json", swipes},
+		{"matches.json", matches},
+		{"blocks.json", blocks},
+		{"reports.json", reports},
+		{"devices.json", devices},
+		{"notification_preferences.json", entity.NotificationPreferencesResponse{
+			Match:     preference.Match,
+			SuperLike: preference.SuperLike,
+		}},
+		{"identities.json", identities},
+		{"account_status_history.json", statusChanges},
+		{"security_events.json", securityEvents},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
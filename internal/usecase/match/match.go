@@ -4,8 +4,11 @@ import (
 	"context"
 
 	"github.com/ghaniswara/dating-app/internal/entity"
+	blockRepo "github.com/ghaniswara/dating-app/internal/repository/block"
 	matchRepo "github.com/ghaniswara/dating-app/internal/repository/match"
+	reportRepo "github.com/ghaniswara/dating-app/internal/repository/report"
 	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
 	"github.com/go-redis/redis"
 )
 
@@ -15,14 +18,27 @@ type IMatchUseCase interface {
 }
 
 type matchUseCase struct {
-	userRepo  userRepo.IUserRepo
-	matchRepo matchRepo.IMatchRepo
+	userRepo   userRepo.IUserRepo
+	matchRepo  matchRepo.IMatchRepo
+	blockRepo  blockRepo.IBlockRepo
+	reportRepo reportRepo.IReportRepo
+	photoCase  photoUseCase.IPhotoUseCase
 }
 
-func NewMatchUseCase(userRepo userRepo.IUserRepo, redisCache *redis.Client, matchRepo matchRepo.IMatchRepo) IMatchUseCase {
+func NewMatchUseCase(
+	userRepo userRepo.IUserRepo,
+	redisCache *redis.Client,
+	matchRepo matchRepo.IMatchRepo,
+	blockRepo blockRepo.IBlockRepo,
+	reportRepo reportRepo.IReportRepo,
+	photoCase photoUseCase.IPhotoUseCase,
+) IMatchUseCase {
 	return &matchUseCase{
-		userRepo:  userRepo,
-		matchRepo: matchRepo,
+		userRepo:   userRepo,
+		matchRepo:  matchRepo,
+		blockRepo:  blockRepo,
+		reportRepo: reportRepo,
+		photoCase:  photoCase,
 	}
 }
 
@@ -39,8 +55,23 @@ func (m *matchUseCase) GetDatingProfiles(ctx context.Context, userID int, exclud
 		return nil, err
 	}
 
+	blockedProfiles, err := m.blockRepo.GetBlockRelatedIDs(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Reported profiles are hidden from the reporter right away, before moderation
+	reportedProfiles, err := m.reportRepo.GetReportedIDs(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
 	excludeProfiles = append(excludeProfiles, likedProfiles...)
 	excludeProfiles = append(excludeProfiles, matchedProfiles...)
+	excludeProfiles = append(excludeProfiles, blockedProfiles...)
+	excludeProfiles = append(excludeProfiles, reportedProfiles...)
 
 	profiles, err := m.matchRepo.GetDatingProfiles(ctx, userID, excludeProfiles, limit)
 
@@ -48,6 +79,10 @@ func (m *matchUseCase) GetDatingProfiles(ctx context.Context, userID int, exclud
 		return nil, err
 	}
 
+	for i := range profiles {
+		m.photoCase.SetURLs(profiles[i].Photos)
+	}
+
 	return profiles, nil
 }
 
@@ -61,6 +96,17 @@ func (m *matchUseCase) SwipeDatingProfile(
 	action entity.Action,
 ) (entity.Outcome, error) {
 
+	// Blocked profiles look nonexistent to both sides
+	isBlocked, err := m.blockRepo.IsBlocked(ctx, userID, likedToUserID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if isBlocked {
+		return entity.OutcomeNotFound, nil
+	}
+
 	likesCount, err := m.matchRepo.GetTodayLikesCount(ctx, userID)
 
 	if err != nil {
@@ -84,6 +130,8 @@ func (m *matchUseCase) SwipeDatingProfile(
 		return 0, err
 	}
 
+	// Realtime events and push notifications are sent by the match worker
+	// consuming the swipe.created and match.created events
 	if Outcome == entity.OutcomeMatch {
 		return entity.OutcomeMatch, nil
 	}
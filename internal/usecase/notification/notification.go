@@ -0,0 +1,166 @@
+package notificationUseCase
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"text/template"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	notificationRepo "github.com/ghaniswara/dating-app/internal/repository/notification"
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+)
+
+type notificationTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+type templateData struct {
+	Name string
+}
+
+var notificationTemplates = map[entity.NotificationType]notificationTemplate{
+	entity.NotificationMatch: {
+		title: template.Must(template.New("match.title").Parse("It's a match!")),
+		body:  template.Must(template.New("match.body").Parse("You and {{.Name}} liked each other")),
+	},
+	entity.NotificationSuperLike: {
+		title: template.Must(template.New("super_like.title").Parse("Someone super liked you")),
+		body:  template.Must(template.New("super_like.body").Parse("{{.Name}} super liked your profile")),
+	},
+}
+
+type INotificationUseCase interface {
+	RegisterDevice(ctx context.Context, userID int, request entity.RegisterDeviceRequest) (*entity.Device, error)
+	UnregisterDevice(ctx context.Context, userID int, token string) error
+
+	GetPreferences(ctx context.Context, userID int) (*entity.NotificationPreference, error)
+	UpdatePreferences(ctx context.Context, userID int, request entity.UpdateNotificationPreferencesRequest) (*entity.NotificationPreference, error)
+
+	// Render the notification and queue it for every device of the user,
+	// fromUserID is the profile which triggered the notification. Calling it
+	// again with the same key only queues the devices that weren't queued yet,
+	// an empty key queues every device each time
+	Notify(ctx context.Context, userID int, notificationType entity.NotificationType, fromUserID int, key string) error
+}
+
+type notificationUseCase struct {
+	notificationRepo notificationRepo.INotificationRepo
+	userRepo         userRepo.IUserRepo
+}
+
+func New(notificationRepo notificationRepo.INotificationRepo, userRepo userRepo.IUserRepo) INotificationUseCase {
+	return &notificationUseCase{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+	}
+}
+
+func (n *notificationUseCase) RegisterDevice(ctx context.Context, userID int, request entity.RegisterDeviceRequest) (*entity.Device, error) {
+	return n.notificationRepo.UpsertDevice(ctx, &entity.Device{
+		UserID:   uint(userID),
+		Platform: request.Platform,
+		Token:    request.Token,
+	})
+}
+
+func (n *notificationUseCase) UnregisterDevice(ctx context.Context, userID int, token string) error {
+	return n.notificationRepo.DeleteDevice(ctx, userID, token)
+}
+
+func (n *notificationUseCase) GetPreferences(ctx context.Context, userID int) (*entity.NotificationPreference, error) {
+	return n.notificationRepo.GetPreference(ctx, userID)
+}
+
+func (n *notificationUseCase) UpdatePreferences(ctx context.Context, userID int, request entity.UpdateNotificationPreferencesRequest) (*entity.NotificationPreference, error) {
+	preference, err := n.notificationRepo.GetPreference(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Match != nil {
+		preference.Match = *request.Match
+	}
+
+	if request.SuperLike != nil {
+		preference.SuperLike = *request.SuperLike
+	}
+
+	if err := n.notificationRepo.SavePreference(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+func (n *notificationUseCase) Notify(ctx context.Context, userID int, notificationType entity.NotificationType, fromUserID int, key string) error {
+	tmpl, ok := notificationTemplates[notificationType]
+
+	if !ok {
+		return nil
+	}
+
+	preference, err := n.notificationRepo.GetPreference(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if !preference.IsEnabled(notificationType) {
+		return nil
+	}
+
+	devices, err := n.notificationRepo.GetDevicesByUserID(ctx, userID)
+
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+
+	fromUser, err := n.userRepo.GetUserByID(ctx, fromUserID)
+
+	if err != nil {
+		return err
+	}
+
+	data := templateData{Name: fromUser.Name}
+
+	var title, body bytes.Buffer
+
+	if err := tmpl.title.Execute(&title, data); err != nil {
+		return err
+	}
+
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		var jobID string
+		if key != "" {
+			jobID = key + ":" + strconv.FormatUint(uint64(device.ID), 10)
+		}
+
+		// A device queued by an earlier call that failed on another device is skipped
+		_, err := n.notificationRepo.EnqueuePush(ctx, entity.PushJob{
+			ID:       jobID,
+			DeviceID: device.ID,
+			Platform: device.Platform,
+			Token:    device.Token,
+			Type:     notificationType,
+			Title:    title.String(),
+			Body:     body.String(),
+			Data: map[string]string{
+				"type":       string(notificationType),
+				"profile_id": strconv.Itoa(fromUserID),
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,421 @@
+package photoUseCase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	photoRepo "github.com/ghaniswara/dating-app/internal/repository/photo"
+	"github.com/ghaniswara/dating-app/pkg/moderation"
+	"github.com/ghaniswara/dating-app/pkg/photo"
+	"github.com/ghaniswara/dating-app/pkg/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxPhotos = 6
+
+	// Size of the uploaded file, before it's processed
+	MaxPhotoSize = 10 << 20
+
+	// Variant handed to the moderator, it keeps the shape of the upload and
+	// only the largest ones are scaled down
+	moderatedVariant = "large"
+
+	maxModerationAttempts = 3
+
+	// Time a worker has to moderate a claimed photo before another one can
+	// claim it
+	moderationLease = 5 * time.Minute
+
+	// Uploads processed at once, each can hold about 160MB at
+	// photo.MaxPixels. The others wait their turn
+	maxConcurrentProcessing = 2
+)
+
+var (
+	ErrPhotoNotFound   = errors.New("photo not found")
+	ErrPhotoLimit      = errors.New("photo limit reached")
+	ErrPhotoTooLarge   = errors.New("photo too large")
+	ErrInvalidPhoto    = errors.New("invalid photo")
+	ErrInvalidPhotoSet = errors.New("photo ids should list every photo once")
+	ErrPhotoNotPending = errors.New("photo has already been reviewed")
+)
+
+type IPhotoUseCase interface {
+	// Strip the metadata, store every variant and add the photo after the
+	// user's last one. It stays pending, hidden from other users, until it's
+	// moderated. ErrInvalidPhoto when it isn't a JPEG or PNG
+	UploadPhoto(ctx context.Context, userID int, data []byte) (*entity.Photo, error)
+	GetPhotos(ctx context.Context, userID int) ([]entity.Photo, error)
+
+	// photoIDs lists every photo of the user in the new order
+	ReorderPhotos(ctx context.Context, userID int, photoIDs []int) ([]entity.Photo, error)
+	SetPrimaryPhoto(ctx context.Context, userID int, photoID int) error
+	DeletePhoto(ctx context.Context, userID int, photoID int) error
+
+	// Delete every photo and its variants, used when the account is erased
+	DeleteUserPhotos(ctx context.Context, userID int) error
+
+	// Fill the URLs of the photos' variants, rejected photos have none
+	SetURLs(photos []entity.Photo)
+
+	// Used by the moderation worker
+	ModeratePending(ctx context.Context, limit int) (int, error)
+
+	// Manual review queue
+	GetReviewQueue(ctx context.Context, limit int, offset int) ([]entity.Photo, error)
+	ApprovePhoto(ctx context.Context, photoID int, request entity.ApprovePhotoRequest) (*entity.Photo, error)
+	RejectPhoto(ctx context.Context, photoID int, request entity.RejectPhotoRequest) (*entity.Photo, error)
+}
+
+type photoUseCase struct {
+	photoRepo  photoRepo.IPhotoRepo
+	blobStore  storage.BlobStore
+	moderator  moderation.Moderator
+	processing chan struct{}
+}
+
+func New(photoRepo photoRepo.IPhotoRepo, blobStore storage.BlobStore, moderator moderation.Moderator) IPhotoUseCase {
+	return &photoUseCase{
+		photoRepo:  photoRepo,
+		blobStore:  blobStore,
+		moderator:  moderator,
+		processing: make(chan struct{}, maxConcurrentProcessing),
+	}
+}
+
+func (p *photoUseCase) UploadPhoto(ctx context.Context, userID int, data []byte) (*entity.Photo, error) {
+	if len(data) > MaxPhotoSize {
+		return nil, ErrPhotoTooLarge
+	}
+
+	// Checked again when the photo is stored, this only saves processing it
+	photos, err := p.photoRepo.GetPhotos(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(photos) >= MaxPhotos {
+		return nil, ErrPhotoLimit
+	}
+
+	processed, err := p.process(ctx, data)
+	if errors.Is(err, photo.ErrUnsupportedType) || errors.Is(err, photo.ErrInvalidImage) || errors.Is(err, photo.ErrTooManyPixels) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPhoto, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	uploaded := &entity.Photo{
+		UserID:     uint(userID),
+		StorageKey: fmt.Sprintf("photos/%d/%s", userID, hex.EncodeToString(suffix)),
+		Width:      processed.Width,
+		Height:     processed.Height,
+		Status:     entity.PhotoPending,
+	}
+
+	for _, variant := range photo.Variants {
+		if err := p.blobStore.Put(ctx, variantKey(uploaded.StorageKey, variant.Name), processed.Variants[variant.Name], "image/jpeg"); err != nil {
+			p.discardVariants(ctx, uploaded.StorageKey)
+			return nil, err
+		}
+	}
+
+	created, err := p.photoRepo.CreatePhoto(ctx, uploaded, MaxPhotos)
+
+	if err != nil || !created {
+		p.discardVariants(ctx, uploaded.StorageKey)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, ErrPhotoLimit
+	}
+
+	uploaded.URLs = p.urls(uploaded.StorageKey)
+
+	return uploaded, nil
+}
+
+func (p *photoUseCase) GetPhotos(ctx context.Context, userID int) ([]entity.Photo, error) {
+	photos, err := p.photoRepo.GetPhotos(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.SetURLs(photos)
+	return photos, nil
+}
+
+func (p *photoUseCase) ReorderPhotos(ctx context.Context, userID int, photoIDs []int) ([]entity.Photo, error) {
+	photos, err := p.photoRepo.GetPhotos(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(photoIDs) != len(photos) {
+		return nil, ErrInvalidPhotoSet
+	}
+
+	for _, existing := range photos {
+		if !slices.Contains(photoIDs, int(existing.ID)) {
+			return nil, ErrInvalidPhotoSet
+		}
+	}
+
+	err = p.photoRepo.ReorderPhotos(ctx, userID, photoIDs)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPhotoSet
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetPhotos(ctx, userID)
+}
+
+func (p *photoUseCase) SetPrimaryPhoto(ctx context.Context, userID int, photoID int) error {
+	err := p.photoRepo.SetPrimaryPhoto(ctx, userID, photoID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPhotoNotFound
+	}
+
+	return err
+}
+
+func (p *photoUseCase) DeletePhoto(ctx context.Context, userID int, photoID int) error {
+	deleted, err := p.photoRepo.DeletePhoto(ctx, userID, photoID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPhotoNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	p.discardVariants(ctx, deleted.StorageKey)
+	return nil
+}
+
+func (p *photoUseCase) DeleteUserPhotos(ctx context.Context, userID int) error {
+	photos, err := p.photoRepo.GetPhotos(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range photos {
+		if err := p.deleteVariants(ctx, existing.StorageKey); err != nil {
+			return err
+		}
+	}
+
+	return p.photoRepo.DeleteUserPhotos(ctx, userID)
+}
+
+func (p *photoUseCase) SetURLs(photos []entity.Photo) {
+	for i := range photos {
+		if photos[i].Status == entity.PhotoRejected {
+			photos[i].URLs = map[string]string{}
+			continue
+		}
+		photos[i].URLs = p.urls(photos[i].StorageKey)
+	}
+}
+
+// A photo the moderator fails on is retried on a later run, and left to a
+// reviewer after maxModerationAttempts
+func (p *photoUseCase) ModeratePending(ctx context.Context, limit int) (int, error) {
+	claimed, err := p.photoRepo.ClaimPending(ctx, limit, moderationLease)
+	if err != nil {
+		return 0, err
+	}
+
+	moderated := 0
+
+	for _, pending := range claimed {
+		status, note, err := p.moderate(ctx, pending)
+
+		if err != nil {
+			flagged, failErr := p.photoRepo.FailModeration(ctx, pending, err, maxModerationAttempts)
+			if failErr != nil {
+				return moderated, failErr
+			}
+			if flagged {
+				log.Println("photo moderation failed", pending.ID, err)
+			}
+			continue
+		}
+
+		saved, err := p.photoRepo.SaveModeration(ctx, pending.ID, status, note)
+		if err != nil {
+			return moderated, err
+		}
+
+		// Deleted or reviewed while it was being moderated
+		if !saved {
+			continue
+		}
+
+		if status == entity.PhotoRejected {
+			p.discardVariants(ctx, pending.StorageKey)
+		}
+
+		moderated++
+	}
+
+	return moderated, nil
+}
+
+func (p *photoUseCase) GetReviewQueue(ctx context.Context, limit int, offset int) ([]entity.Photo, error) {
+	photos, err := p.photoRepo.GetReviewQueue(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	p.SetURLs(photos)
+	return photos, nil
+}
+
+func (p *photoUseCase) ApprovePhoto(ctx context.Context, photoID int, request entity.ApprovePhotoRequest) (*entity.Photo, error) {
+	return p.reviewPhoto(ctx, photoID, entity.PhotoApproved, request.Note)
+}
+
+func (p *photoUseCase) RejectPhoto(ctx context.Context, photoID int, request entity.RejectPhotoRequest) (*entity.Photo, error) {
+	return p.reviewPhoto(ctx, photoID, entity.PhotoRejected, request.Reason)
+}
+
+// Helper
+
+func (p *photoUseCase) process(ctx context.Context, data []byte) (*photo.Processed, error) {
+	select {
+	case p.processing <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.processing }()
+
+	return photo.Process(data)
+}
+
+func (p *photoUseCase) moderate(ctx context.Context, pending entity.Photo) (entity.PhotoStatus, string, error) {
+	data, err := p.readVariant(ctx, pending.StorageKey, moderatedVariant)
+	if err != nil {
+		return 0, "", err
+	}
+
+	result, err := p.moderator.Moderate(ctx, data)
+	if err != nil {
+		return 0, "", err
+	}
+
+	switch result.Verdict {
+	case moderation.VerdictApprove:
+		return entity.PhotoApproved, result.Reason, nil
+	case moderation.VerdictReject:
+		return entity.PhotoRejected, result.Reason, nil
+	default:
+		return entity.PhotoPending, result.Reason, nil
+	}
+}
+
+// Only pending photos can be reviewed, flagged or not
+func (p *photoUseCase) reviewPhoto(ctx context.Context, photoID int, status entity.PhotoStatus, note string) (*entity.Photo, error) {
+	reviewed, err := p.photoRepo.GetPhotoByID(ctx, photoID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewed.Status != entity.PhotoPending {
+		return nil, ErrPhotoNotPending
+	}
+
+	now := time.Now()
+
+	updated, err := p.photoRepo.ReviewPhoto(ctx, photoID, status, note, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reviewed by someone else in the meantime
+	if !updated {
+		return nil, ErrPhotoNotPending
+	}
+
+	reviewed.Status = status
+	reviewed.ModerationNote = note
+	reviewed.ReviewedAt = &now
+	reviewed.URLs = map[string]string{}
+
+	if status == entity.PhotoRejected {
+		p.discardVariants(ctx, reviewed.StorageKey)
+	} else {
+		reviewed.URLs = p.urls(reviewed.StorageKey)
+	}
+
+	return reviewed, nil
+}
+
+func (p *photoUseCase) readVariant(ctx context.Context, storageKey, variant string) ([]byte, error) {
+	reader, err := p.blobStore.Open(ctx, variantKey(storageKey, variant))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func (p *photoUseCase) urls(storageKey string) map[string]string {
+	urls := map[string]string{}
+	for _, variant := range photo.Variants {
+		urls[variant.Name] = p.blobStore.URL(variantKey(storageKey, variant.Name))
+	}
+	return urls
+}
+
+// A leftover variant is only wasted space, the photo is gone either way
+func (p *photoUseCase) discardVariants(ctx context.Context, storageKey string) {
+	if err := p.deleteVariants(ctx, storageKey); err != nil {
+		log.Println("error deleting photo variants", storageKey, err)
+	}
+}
+
+func (p *photoUseCase) deleteVariants(ctx context.Context, storageKey string) error {
+	for _, variant := range photo.Variants {
+		if err := p.blobStore.Delete(ctx, variantKey(storageKey, variant.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func variantKey(storageKey, variant string) string {
+	return storageKey + "/" + variant + ".jpg"
+}
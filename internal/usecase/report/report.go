@@ -0,0 +1,187 @@
+package reportUseCase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	reportRepo "github.com/ghaniswara/dating-app/internal/repository/report"
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	accountUseCase "github.com/ghaniswara/dating-app/internal/usecase/account"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReportSelf       = errors.New("cannot report yourself")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrReportNotFound   = errors.New("report not found")
+	ErrReportNotPending = errors.New("report has already been reviewed")
+)
+
+type IReportUseCase interface {
+	ReportUser(ctx context.Context, reporterID int, request entity.CreateReportRequest) (*entity.Report, error)
+
+	// Moderation queue
+	GetReports(ctx context.Context, status entity.ReportStatus, limit int, offset int) ([]entity.Report, error)
+	GetReport(ctx context.Context, reportID int) (*entity.Report, error)
+	// The reviewer is recorded on the report
+	DismissReport(ctx context.Context, reportID int, request entity.DismissReportRequest, reviewer *entity.User) (*entity.Report, error)
+
+	// The reviewer is recorded on the report and as the actor of a suspension
+	// or ban, and must outrank the reported user
+	ActionReport(ctx context.Context, reportID int, request entity.ResolveReportRequest, reviewer *entity.User) (*entity.Report, error)
+}
+
+type reportUseCase struct {
+	reportRepo  reportRepo.IReportRepo
+	userRepo    userRepo.IUserRepo
+	accountCase accountUseCase.IAccountUseCase
+}
+
+func New(reportRepo reportRepo.IReportRepo, userRepo userRepo.IUserRepo, accountCase accountUseCase.IAccountUseCase) IReportUseCase {
+	return &reportUseCase{
+		reportRepo:  reportRepo,
+		userRepo:    userRepo,
+		accountCase: accountCase,
+	}
+}
+
+func (r *reportUseCase) ReportUser(ctx context.Context, reporterID int, request entity.CreateReportRequest) (*entity.Report, error) {
+	if reporterID == request.ReportedUserID {
+		return nil, ErrReportSelf
+	}
+
+	if _, err := r.userRepo.GetUserByID(ctx, request.ReportedUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	report := entity.Report{
+		ReporterID: uint(reporterID),
+		ReportedID: uint(request.ReportedUserID),
+		TargetType: request.TargetType,
+		Reason:     request.Reason,
+		Details:    request.Details,
+		Status:     entity.ReportStatusPending,
+	}
+
+	return r.reportRepo.CreateReport(ctx, &report)
+}
+
+func (r *reportUseCase) GetReports(ctx context.Context, status entity.ReportStatus, limit int, offset int) ([]entity.Report, error) {
+	return r.reportRepo.GetReportsByStatus(ctx, status, limit, offset)
+}
+
+func (r *reportUseCase) GetReport(ctx context.Context, reportID int) (*entity.Report, error) {
+	report, err := r.reportRepo.GetReportByID(ctx, reportID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReportNotFound
+	}
+
+	return report, err
+}
+
+func (r *reportUseCase) DismissReport(ctx context.Context, reportID int, request entity.DismissReportRequest, reviewer *entity.User) (*entity.Report, error) {
+	report, err := r.getPendingReport(ctx, reportID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report.Status = entity.ReportStatusDismissed
+	report.ResolutionNote = request.Note
+	report.ReviewerID = &reviewer.ID
+	report.ReviewedAt = &now
+
+	if err := r.resolve(ctx, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (r *reportUseCase) ActionReport(ctx context.Context, reportID int, request entity.ResolveReportRequest, reviewer *entity.User) (*entity.Report, error) {
+	report, err := r.getPendingReport(ctx, reportID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reported, err := r.userRepo.GetUserByID(ctx, int(report.ReportedID))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := accountUseCase.CheckModerator(reviewer, reported); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	action := request.Action
+	report.Status = entity.ReportStatusActioned
+	report.Action = &action
+	report.ResolutionNote = request.Note
+	report.ReviewerID = &reviewer.ID
+	report.ReviewedAt = &now
+
+	// Resolve first so two moderators can't both act on the same report
+	if err := r.resolve(ctx, report); err != nil {
+		return nil, err
+	}
+
+	reason := fmt.Sprintf("report #%d: %s", report.ID, request.Note)
+
+	// A warning only resolves the report, the account stays active
+	switch request.Action {
+	case entity.ModerationSuspend:
+		until := now.AddDate(0, 0, request.SuspendDays)
+		err = r.accountCase.SuspendUser(ctx, int(report.ReportedID), until, reason, &reviewer.ID)
+	case entity.ModerationBan:
+		err = r.accountCase.BanUser(ctx, int(report.ReportedID), reason, &reviewer.ID)
+	}
+
+	if err != nil {
+		// Back to the queue so the report isn't marked actioned without an action
+		if reopenErr := r.reportRepo.ReopenReport(ctx, report.ID); reopenErr != nil {
+			return nil, errors.Join(err, reopenErr)
+		}
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (r *reportUseCase) getPendingReport(ctx context.Context, reportID int) (*entity.Report, error) {
+	report, err := r.GetReport(ctx, reportID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Status != entity.ReportStatusPending {
+		return nil, ErrReportNotPending
+	}
+
+	return report, nil
+}
+
+func (r *reportUseCase) resolve(ctx context.Context, report *entity.Report) error {
+	resolved, err := r.reportRepo.ResolveReport(ctx, report)
+
+	if err != nil {
+		return err
+	}
+
+	if !resolved {
+		return ErrReportNotPending
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package accountWorker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	userRepo "github.com/ghaniswara/dating-app/internal/repository/user"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+)
+
+const (
+	batchSize    = 100
+	pollInterval = 10 * time.Minute
+
+	// A failed erasure is retried after 10m, 20m, 40m and so on, up to a day
+	maxRetryDelay = 24 * time.Hour
+)
+
+// Eraser erases deleted accounts once their grace period has passed. An
+// erasure failing halfway is retried with a backoff, the user stays due
+// meanwhile and the others in the batch go on
+type Eraser struct {
+	userRepo  userRepo.IUserRepo
+	photoCase photoUseCase.IPhotoUseCase
+}
+
+func NewEraser(userRepo userRepo.IUserRepo, photoCase photoUseCase.IPhotoUseCase) *Eraser {
+	return &Eraser{
+		userRepo:  userRepo,
+		photoCase: photoCase,
+	}
+}
+
+func (e *Eraser) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.erase(ctx, now)
+		}
+	}
+}
+
+// Keep erasing while full batches come back so a backlog drains quickly
+func (e *Eraser) erase(ctx context.Context, now time.Time) {
+	for {
+		users, err := e.userRepo.GetUsersDueForErasure(ctx, now, batchSize)
+		if err != nil {
+			log.Println("error getting users due for erasure", err)
+			return
+		}
+
+		for _, user := range users {
+			if err := e.eraseUser(ctx, int(user.ID)); err != nil {
+				log.Println("error erasing user", user.ID, "attempt", user.ErasureAttempts+1, err)
+
+				// Delayed users drop out of the next batch, so the loop ends
+				// even when every erasure fails
+				if err := e.userRepo.DelayErasure(ctx, int(user.ID), now.Add(retryDelay(user.ErasureAttempts+1))); err != nil {
+					log.Println("error delaying erasure of user", user.ID, err)
+					return
+				}
+			}
+		}
+
+		if len(users) < batchSize {
+			return
+		}
+	}
+}
+
+func (e *Eraser) eraseUser(ctx context.Context, userID int) error {
+	// Photos live in the blob store, they go before the rows pointing to them
+	if err := e.photoCase.DeleteUserPhotos(ctx, userID); err != nil {
+		return err
+	}
+
+	return e.userRepo.EraseUser(ctx, userID)
+}
+
+// Helper
+
+func retryDelay(attempts int) time.Duration {
+	delay := pollInterval
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,67 @@
+package exportWorker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	exportUseCase "github.com/ghaniswara/dating-app/internal/usecase/export"
+)
+
+const (
+	// The whole batch is built one export at a time within the claim's lease
+	batchSize       = 10
+	pollInterval    = 5 * time.Second
+	cleanupInterval = time.Hour
+)
+
+// Builder builds the archives of requested data exports and drops them once
+// they expire
+type Builder struct {
+	exportCase exportUseCase.IExportUseCase
+}
+
+func NewBuilder(exportCase exportUseCase.IExportUseCase) *Builder {
+	return &Builder{
+		exportCase: exportCase,
+	}
+}
+
+func (b *Builder) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.build(ctx)
+
+			if now.Sub(lastCleanup) >= cleanupInterval {
+				if _, err := b.exportCase.ExpireArchives(ctx, now); err != nil {
+					log.Println("error expiring data export archives", err)
+				}
+				lastCleanup = now
+			}
+		}
+	}
+}
+
+// Keep building while full batches come back so a backlog drains quickly
+func (b *Builder) build(ctx context.Context) {
+	for {
+		built, err := b.exportCase.BuildPending(ctx, batchSize)
+
+		if err != nil {
+			log.Println("error building data exports", err)
+			return
+		}
+
+		if built < batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package matchWorker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/events"
+	eventUseCase "github.com/ghaniswara/dating-app/internal/usecase/event"
+	notificationUseCase "github.com/ghaniswara/dating-app/internal/usecase/notification"
+)
+
+const consumerGroup = "match-notifier"
+
+// Consumer turns swipe domain events into realtime events and push notifications
+type Consumer struct {
+	subscriber       events.Subscriber
+	deduplicator     events.Deduplicator
+	eventCase        eventUseCase.IEventUseCase
+	notificationCase notificationUseCase.INotificationUseCase
+}
+
+func NewConsumer(
+	subscriber events.Subscriber,
+	deduplicator events.Deduplicator,
+	eventCase eventUseCase.IEventUseCase,
+	notificationCase notificationUseCase.INotificationUseCase,
+) *Consumer {
+	return &Consumer{
+		subscriber:       subscriber,
+		deduplicator:     deduplicator,
+		eventCase:        eventCase,
+		notificationCase: notificationCase,
+	}
+}
+
+func (c *Consumer) Run(ctx context.Context) {
+	go c.subscribe(ctx, entity.TopicMatchCreated, c.handleMatchCreated)
+	c.subscribe(ctx, entity.TopicSwipeCreated, c.handleSwipeCreated)
+}
+
+func (c *Consumer) subscribe(ctx context.Context, topic string, handler events.Handler) {
+	if err := c.subscriber.Subscribe(ctx, topic, consumerGroup, handler); err != nil {
+		log.Println("error subscribing to", topic, err)
+	}
+}
+
+func (c *Consumer) handleMatchCreated(ctx context.Context, msg events.Message) error {
+	var event entity.MatchCreatedEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		// Retrying won't fix a malformed payload
+		log.Println("error decoding match.created", msg.ID, err)
+		return nil
+	}
+
+	// Each step is recorded once done, a redelivery (or the outbox publishing
+	// the event again) only repeats the steps that didn't complete
+	err := c.once(ctx, msg, "event:user", func() error {
+		return c.eventCase.Publish(ctx, event.UserID, entity.EventMatch, entity.MatchEventData{ProfileID: event.MatchedUserID})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.once(ctx, msg, "event:matched", func() error {
+		return c.eventCase.Publish(ctx, event.MatchedUserID, entity.EventMatch, entity.MatchEventData{ProfileID: event.UserID})
+	})
+	if err != nil {
+		return err
+	}
+
+	// The matched user swiped earlier and is likely offline
+	return c.once(ctx, msg, "notify", func() error {
+		return c.notificationCase.Notify(ctx, event.MatchedUserID, entity.NotificationMatch, event.UserID, stepKey(msg, "notify"))
+	})
+}
+
+func (c *Consumer) handleSwipeCreated(ctx context.Context, msg events.Message) error {
+	var event entity.SwipeCreatedEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		log.Println("error decoding swipe.created", msg.ID, err)
+		return nil
+	}
+
+	if event.Outcome == entity.OutcomeMissed {
+		return c.once(ctx, msg, "event:missed", func() error {
+			return c.eventCase.Publish(ctx, event.UserID, entity.EventMissed, entity.MatchEventData{ProfileID: event.ToID})
+		})
+	}
+
+	if event.Outcome == entity.OutcomeNoLike && event.Action == entity.ActionSuperLike {
+		return c.once(ctx, msg, "notify", func() error {
+			return c.notificationCase.Notify(ctx, event.ToID, entity.NotificationSuperLike, event.UserID, stepKey(msg, "notify"))
+		})
+	}
+
+	return nil
+}
+
+// Helper
+
+// Runs step unless it already completed for the message's key
+func (c *Consumer) once(ctx context.Context, msg events.Message, step string, fn func() error) error {
+	if msg.Key == "" {
+		return fn()
+	}
+
+	key := stepKey(msg, step)
+
+	seen, err := c.deduplicator.Seen(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if seen {
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return c.deduplicator.MarkSeen(ctx, key)
+}
+
+// Empty for messages without a key, they can't be told apart when redelivered
+func stepKey(msg events.Message, step string) string {
+	if msg.Key == "" {
+		return ""
+	}
+
+	return strings.Join([]string{consumerGroup, msg.Topic, msg.Key, step}, ":")
+}
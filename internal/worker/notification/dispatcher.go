@@ -0,0 +1,140 @@
+package notificationWorker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	notificationRepo "github.com/ghaniswara/dating-app/internal/repository/notification"
+	"github.com/ghaniswara/dating-app/pkg/push"
+)
+
+const (
+	maxAttempts   = 5
+	baseBackoff   = 5 * time.Second
+	pollTimeout   = 5 * time.Second
+	promoteTicker = time.Second
+
+	// A send is well under this, a job processing for longer belonged to a
+	// dispatcher that died and is handed out again
+	processingLease = time.Minute
+)
+
+// Dispatcher drains the push queue and hands each job to the provider of the device platform
+type Dispatcher struct {
+	notificationRepo notificationRepo.INotificationRepo
+	providers        map[entity.Platform]push.Provider
+}
+
+func NewDispatcher(notificationRepo notificationRepo.INotificationRepo, providers map[entity.Platform]push.Provider) *Dispatcher {
+	return &Dispatcher{
+		notificationRepo: notificationRepo,
+		providers:        providers,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	go d.promoteRetries(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		job, err := d.notificationRepo.DequeuePush(ctx, pollTimeout)
+
+		if err != nil {
+			log.Println("error dequeuing push notification", err)
+			time.Sleep(pollTimeout)
+			continue
+		}
+
+		if job == nil {
+			continue
+		}
+
+		d.dispatch(ctx, *job)
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, job entity.PushJob) {
+	provider, ok := d.providers[job.Platform]
+
+	if !ok {
+		log.Println("no push provider configured for platform", job.Platform)
+		d.ack(ctx, job)
+		return
+	}
+
+	err := provider.Send(ctx, push.Message{
+		Token: job.Token,
+		Title: job.Title,
+		Body:  job.Body,
+		Data:  job.Data,
+	})
+
+	if err == nil {
+		d.ack(ctx, job)
+		return
+	}
+
+	if errors.Is(err, push.ErrInvalidToken) {
+		if err := d.notificationRepo.DeleteDeviceByToken(ctx, job.Token); err != nil {
+			log.Println("error deleting invalid device", err)
+		}
+		d.ack(ctx, job)
+		return
+	}
+
+	job.Attempts++
+
+	if job.Attempts >= maxAttempts {
+		log.Println("push notification exhausted retries", job.ID, err)
+		if err := d.notificationRepo.DeadLetterPush(ctx, job); err != nil {
+			log.Println("error dead lettering push notification", err)
+		}
+		return
+	}
+
+	if err := d.notificationRepo.SchedulePushRetry(ctx, job, time.Now().Add(backoff(job.Attempts))); err != nil {
+		log.Println("error scheduling push notification retry", err)
+	}
+}
+
+func (d *Dispatcher) promoteRetries(ctx context.Context) {
+	ticker := time.NewTicker(promoteTicker)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := d.notificationRepo.PromoteDuePushRetries(ctx, now); err != nil {
+				log.Println("error promoting push notification retries", err)
+			}
+
+			if _, err := d.notificationRepo.RequeueStalePushes(ctx, now, processingLease); err != nil {
+				log.Println("error requeuing stale push notifications", err)
+			}
+		}
+	}
+}
+
+// Helper
+
+// An unacknowledged job is sent again once its lease runs out
+func (d *Dispatcher) ack(ctx context.Context, job entity.PushJob) {
+	if err := d.notificationRepo.AckPush(ctx, job); err != nil {
+		log.Println("error acknowledging push notification", job.ID, err)
+	}
+}
+
+// Exponential backoff, 5s, 10s, 20s, 40s
+func backoff(attempts int) time.Duration {
+	return baseBackoff * time.Duration(1<<(attempts-1))
+}
@@ -0,0 +1,139 @@
+package outboxWorker
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/events"
+	outboxRepo "github.com/ghaniswara/dating-app/internal/repository/outbox"
+)
+
+const (
+	batchSize       = 100
+	pollInterval    = 500 * time.Millisecond
+	cleanupInterval = time.Hour
+	retention       = 7 * 24 * time.Hour
+
+	// How long a claimed batch is held before another relay may take it
+	claimLease = time.Minute
+
+	// Failed events are retried with an exponential backoff, after maxAttempts
+	// they're dead lettered so a bad event isn't retried forever
+	maxAttempts  = 10
+	retryBackoff = time.Second
+	maxBackoff   = 5 * time.Minute
+)
+
+// Relay publishes outbox rows to the event bus. A crash between publishing and
+// marking the row means the event is published again, hence at-least-once
+type Relay struct {
+	outboxRepo outboxRepo.IOutboxRepo
+	publisher  events.Publisher
+}
+
+func NewRelay(outboxRepo outboxRepo.IOutboxRepo, publisher events.Publisher) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.relay(ctx)
+
+			if now.Sub(lastCleanup) >= cleanupInterval {
+				if _, err := r.outboxRepo.DeletePublishedBefore(ctx, now.Add(-retention)); err != nil {
+					log.Println("error cleaning up outbox", err)
+				}
+				lastCleanup = now
+			}
+		}
+	}
+}
+
+// Keep relaying while full batches come back so a backlog drains quickly
+func (r *Relay) relay(ctx context.Context) {
+	for {
+		published, err := r.Flush(ctx)
+
+		if err != nil {
+			log.Println("error relaying outbox events", err)
+			return
+		}
+
+		if published < batchSize {
+			return
+		}
+	}
+}
+
+// Flush publishes one batch of pending events and returns how many went out.
+// The batch stops at the first failure, the bus is likely down, and the rest of
+// it is released for the next run. Only the failed event waits for its retry
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	pending, err := r.outboxRepo.ClaimPending(ctx, batchSize, claimLease)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range pending {
+		err := r.publisher.Publish(ctx, events.Message{
+			Key:     strconv.FormatUint(uint64(event.ID), 10),
+			Topic:   event.Topic,
+			Payload: []byte(event.Payload),
+		})
+
+		if err != nil {
+			dead, markErr := r.outboxRepo.MarkFailed(ctx, event, err, time.Now().Add(backoff(event.Attempts)), maxAttempts)
+			if markErr != nil {
+				return i, markErr
+			}
+
+			if dead {
+				log.Println("outbox event dead lettered", event.ID, event.Topic, err)
+			}
+
+			return i, r.release(ctx, pending[i+1:])
+		}
+
+		if err := r.outboxRepo.MarkPublished(ctx, event.ID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(pending), nil
+}
+
+func (r *Relay) release(ctx context.Context, rest []entity.OutboxEvent) error {
+	ids := make([]uint, 0, len(rest))
+	for _, event := range rest {
+		ids = append(ids, event.ID)
+	}
+
+	return r.outboxRepo.ReleaseClaims(ctx, ids)
+}
+
+// Helper
+
+func backoff(attempts int) time.Duration {
+	delay := retryBackoff
+	for i := 0; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
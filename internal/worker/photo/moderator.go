@@ -0,0 +1,57 @@
+package photoWorker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+)
+
+const (
+	// The whole batch is moderated one photo at a time within the claim's lease
+	batchSize    = 10
+	pollInterval = 5 * time.Second
+)
+
+// Moderator hands uploaded photos to the moderator, the ones it can't decide
+// on are flagged for the admin review queue
+type Moderator struct {
+	photoCase photoUseCase.IPhotoUseCase
+}
+
+func NewModerator(photoCase photoUseCase.IPhotoUseCase) *Moderator {
+	return &Moderator{
+		photoCase: photoCase,
+	}
+}
+
+func (m *Moderator) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.moderate(ctx)
+		}
+	}
+}
+
+// Keep moderating while full batches come back so a backlog drains quickly
+func (m *Moderator) moderate(ctx context.Context) {
+	for {
+		moderated, err := m.photoCase.ModeratePending(ctx, batchSize)
+
+		if err != nil {
+			log.Println("error moderating photos", err)
+			return
+		}
+
+		if moderated < batchSize {
+			return
+		}
+	}
+}
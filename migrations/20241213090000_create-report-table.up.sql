@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGSERIAL PRIMARY KEY,
+    reporter_id BIGINT NOT NULL REFERENCES users(id),
+    reported_id BIGINT NOT NULL REFERENCES users(id),
+    target_type VARCHAR(16) NOT NULL,
+    target_id BIGINT,
+    reason VARCHAR(32) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status SMALLINT NOT NULL DEFAULT 1,
+    action VARCHAR(16),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reports_status ON reports (status, created_at);
+CREATE INDEX idx_reports_reporter_id ON reports (reporter_id);
+CREATE INDEX idx_reports_reported_id ON reports (reported_id);
+
+CREATE TRIGGER update_report_updated_at
+BEFORE UPDATE ON reports
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS account_status_audits;
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
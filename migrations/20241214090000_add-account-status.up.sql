@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN status SMALLINT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+
+CREATE INDEX idx_users_status ON users (status);
+
+CREATE TABLE IF NOT EXISTS account_status_audits (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    from_status SMALLINT NOT NULL,
+    to_status SMALLINT NOT NULL,
+    suspended_until TIMESTAMP,
+    reason TEXT NOT NULL DEFAULT '',
+    actor_id BIGINT REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_account_status_audits_user_id ON account_status_audits (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS password_cost;
+ALTER TABLE users DROP COLUMN IF EXISTS password_algorithm;
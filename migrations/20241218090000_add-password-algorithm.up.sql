@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN password_algorithm VARCHAR(16) NOT NULL DEFAULT 'bcrypt_email';
+ALTER TABLE users ADD COLUMN password_cost SMALLINT NOT NULL DEFAULT 12;
+
+-- Existing hashes keep the email in the salt, new ones don't
+ALTER TABLE users ALTER COLUMN password_algorithm SET DEFAULT 'bcrypt';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS security_audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id),
+    event VARCHAR(32) NOT NULL,
+    identifier VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_security_audit_logs_user_id ON security_audit_logs (user_id);
+CREATE INDEX idx_security_audit_logs_ip ON security_audit_logs (ip, created_at);
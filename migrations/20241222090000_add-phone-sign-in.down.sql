@@ -0,0 +1,9 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_or_phone;
+
+-- Email is required again, users who signed up with a phone get a placeholder
+-- on the reserved .invalid domain so no mail is ever sent to it
+UPDATE users SET email = 'phone' || TRIM(LEADING '+' FROM phone) || '@phone.invalid' WHERE email IS NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,7 @@
+-- Users signing up with a phone number have no email
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+
+ALTER TABLE users ADD COLUMN phone VARCHAR(16) UNIQUE;
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMP;
+
+ALTER TABLE users ADD CONSTRAINT users_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL);
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_algorithm VARCHAR(16) NOT NULL DEFAULT 'bcrypt';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_cost SMALLINT NOT NULL DEFAULT 12;
+
+UPDATE users
+SET password = c.password_hash,
+    password_algorithm = c.password_algorithm,
+    password_cost = c.password_cost
+FROM auth_credentials c
+WHERE c.user_id = users.id;
+
+ALTER TABLE users ALTER COLUMN password DROP DEFAULT;
+
+DROP TABLE IF EXISTS auth_credentials;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS auth_credentials (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id),
+    password_hash VARCHAR(255) NOT NULL,
+    password_algorithm VARCHAR(16) NOT NULL,
+    password_cost SMALLINT NOT NULL,
+    password_changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Users who signed up with a provider or a phone have an empty password and
+-- get no credential
+INSERT INTO auth_credentials (user_id, password_hash, password_algorithm, password_cost, password_changed_at, created_at, updated_at)
+SELECT id, password, password_algorithm, password_cost, updated_at, created_at, updated_at
+FROM users
+WHERE password <> '';
+
+ALTER TABLE users DROP COLUMN password;
+ALTER TABLE users DROP COLUMN password_algorithm;
+ALTER TABLE users DROP COLUMN password_cost;
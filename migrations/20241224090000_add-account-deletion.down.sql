@@ -0,0 +1,11 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_or_phone;
+
+-- Email is required again, users who were erased get a placeholder
+-- on the reserved .invalid domain like in add-phone-sign-in
+UPDATE users SET email = 'erased' || id || '@erased.invalid' WHERE email IS NULL AND phone IS NULL;
+
+ALTER TABLE users ADD CONSTRAINT users_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL);
+
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
@@ -0,0 +1,11 @@
+-- A deleted account is erased once deletion_scheduled_at has passed, until
+-- then signing in cancels the deletion
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMP;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Erased users keep their row for the audit log and reports, without any
+-- email or phone
+ALTER TABLE users DROP CONSTRAINT users_email_or_phone;
+ALTER TABLE users ADD CONSTRAINT users_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL OR erased_at IS NOT NULL);
@@ -0,0 +1,23 @@
+-- Personal data exports, the archive is built by the export worker and
+-- dropped once it expires
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    status SMALLINT NOT NULL DEFAULT 1,
+    archive BYTEA,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports (user_id, created_at);
+CREATE INDEX idx_data_exports_pending ON data_exports (id) WHERE status = 1;
+CREATE INDEX idx_data_exports_expires_at ON data_exports (expires_at) WHERE status = 2;
+
+CREATE TRIGGER update_data_export_updated_at
+BEFORE UPDATE ON data_exports
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
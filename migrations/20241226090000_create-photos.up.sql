@@ -0,0 +1,22 @@
+-- Variants of a photo are stored in the blob store under storage_key
+CREATE TABLE IF NOT EXISTS photos (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    position SMALLINT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_photos_user_id ON photos (user_id, position);
+
+-- At most one primary photo per user
+CREATE UNIQUE INDEX idx_photos_primary ON photos (user_id) WHERE is_primary;
+
+CREATE TRIGGER update_photo_updated_at
+BEFORE UPDATE ON photos
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_photos_review_queue;
+DROP INDEX IF EXISTS idx_photos_moderation;
+ALTER TABLE photos DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS flagged_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS moderation_attempts;
+ALTER TABLE photos DROP COLUMN IF EXISTS moderation_note;
+ALTER TABLE photos DROP COLUMN IF EXISTS status;
@@ -0,0 +1,10 @@
+-- Existing photos start pending so they go through moderation too
+ALTER TABLE photos ADD COLUMN status SMALLINT NOT NULL DEFAULT 1;
+ALTER TABLE photos ADD COLUMN moderation_note TEXT NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN moderation_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN flagged_at TIMESTAMP;
+ALTER TABLE photos ADD COLUMN reviewed_at TIMESTAMP;
+
+-- Photos waiting for the moderator, and the ones flagged for a reviewer
+CREATE INDEX idx_photos_moderation ON photos (id) WHERE status = 1 AND flagged_at IS NULL;
+CREATE INDEX idx_photos_review_queue ON photos (flagged_at) WHERE status = 1 AND flagged_at IS NOT NULL;
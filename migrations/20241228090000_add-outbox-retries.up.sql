@@ -0,0 +1,7 @@
+-- A failed event waits until available_at, claimed ones are leased until then.
+-- Events that keep failing are dead lettered so they stop blocking the relay
+ALTER TABLE outbox ADD COLUMN available_at TIMESTAMP;
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS update_totp_credential_updated_at ON totp_credentials;
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS update_auth_credential_updated_at ON auth_credentials;
@@ -0,0 +1,4 @@
+CREATE TRIGGER update_auth_credential_updated_at
+BEFORE UPDATE ON auth_credentials
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- Exports are claimed by a worker until claimed_until and built outside the
+-- claiming transaction
+ALTER TABLE data_exports ADD COLUMN claimed_until TIMESTAMP;
@@ -0,0 +1 @@
+ALTER TABLE photos DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,3 @@
+-- Photos are claimed by a moderation worker until claimed_until and moderated
+-- outside the claiming transaction
+ALTER TABLE photos ADD COLUMN claimed_until TIMESTAMP;
@@ -0,0 +1,20 @@
+-- Emails differing only in case belong to the same person, such accounts have
+-- to be merged by hand before lowercasing
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE email IS NOT NULL GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'users have emails differing only in case';
+    END IF;
+END $$;
+
+-- Legacy hashes are salted with the email as it was stored, they keep it
+-- until the password is rehashed
+ALTER TABLE auth_credentials ADD COLUMN password_salt VARCHAR(255);
+UPDATE auth_credentials SET password_salt = users.email
+FROM users
+WHERE users.id = auth_credentials.user_id AND auth_credentials.password_algorithm = 'bcrypt_email';
+
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_email_lower_key ON users (LOWER(email));
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS erasure_retry_at;
+ALTER TABLE users DROP COLUMN IF EXISTS erasure_attempts;
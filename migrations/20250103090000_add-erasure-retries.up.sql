@@ -0,0 +1,4 @@
+-- A failed erasure is retried after erasure_retry_at, the other due users
+-- aren't held up by it
+ALTER TABLE users ADD COLUMN erasure_attempts SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN erasure_retry_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- The moderator who dismissed or actioned the report
+ALTER TABLE reports ADD COLUMN reviewer_id BIGINT REFERENCES users(id);
@@ -0,0 +1,28 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+ALTER TABLE users ADD COLUMN username VARCHAR(255) UNIQUE;
+ALTER TABLE users ADD COLUMN phone VARCHAR(16) UNIQUE;
+
+UPDATE users SET email = auth_credentials.email, username = auth_credentials.username, phone = auth_credentials.phone
+FROM auth_credentials
+WHERE auth_credentials.user_id = users.id;
+
+CREATE UNIQUE INDEX users_email_lower_key ON users (LOWER(email));
+ALTER TABLE users ADD CONSTRAINT users_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL OR erased_at IS NOT NULL);
+
+DROP INDEX IF EXISTS auth_credentials_phone_key;
+DROP INDEX IF EXISTS auth_credentials_username_key;
+DROP INDEX IF EXISTS auth_credentials_email_lower_key;
+ALTER TABLE auth_credentials DROP CONSTRAINT IF EXISTS auth_credentials_password;
+ALTER TABLE auth_credentials DROP CONSTRAINT IF EXISTS auth_credentials_email_or_phone;
+ALTER TABLE auth_credentials DROP COLUMN IF EXISTS phone;
+ALTER TABLE auth_credentials DROP COLUMN IF EXISTS username;
+ALTER TABLE auth_credentials DROP COLUMN IF EXISTS email;
+
+-- Users without a password had no credential
+DELETE FROM auth_credentials WHERE password_hash IS NULL;
+
+ALTER TABLE auth_credentials ALTER COLUMN password_changed_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE auth_credentials ALTER COLUMN password_changed_at SET NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_cost SET NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_algorithm SET NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_hash SET NOT NULL;
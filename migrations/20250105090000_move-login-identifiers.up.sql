@@ -0,0 +1,35 @@
+-- The email, username and phone users sign in with move next to the password.
+-- Every user gets a credential, the password becomes optional
+ALTER TABLE auth_credentials ALTER COLUMN password_hash DROP NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_algorithm DROP NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_cost DROP NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_changed_at DROP NOT NULL;
+ALTER TABLE auth_credentials ALTER COLUMN password_changed_at DROP DEFAULT;
+
+ALTER TABLE auth_credentials ADD COLUMN email VARCHAR(255);
+ALTER TABLE auth_credentials ADD COLUMN username VARCHAR(255);
+ALTER TABLE auth_credentials ADD COLUMN phone VARCHAR(16);
+
+-- Erased users have no identifiers left and keep no credential
+INSERT INTO auth_credentials (user_id, created_at, updated_at)
+SELECT id, created_at, updated_at
+FROM users
+WHERE erased_at IS NULL AND id NOT IN (SELECT user_id FROM auth_credentials);
+
+UPDATE auth_credentials SET email = users.email, username = users.username, phone = users.phone
+FROM users
+WHERE users.id = auth_credentials.user_id;
+
+ALTER TABLE auth_credentials ADD CONSTRAINT auth_credentials_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL);
+ALTER TABLE auth_credentials ADD CONSTRAINT auth_credentials_password CHECK (
+    password_hash IS NULL OR (password_algorithm IS NOT NULL AND password_cost IS NOT NULL AND password_changed_at IS NOT NULL)
+);
+CREATE UNIQUE INDEX auth_credentials_email_lower_key ON auth_credentials (LOWER(email));
+CREATE UNIQUE INDEX auth_credentials_username_key ON auth_credentials (username);
+CREATE UNIQUE INDEX auth_credentials_phone_key ON auth_credentials (phone);
+
+ALTER TABLE users DROP CONSTRAINT users_email_or_phone;
+DROP INDEX users_email_lower_key;
+ALTER TABLE users DROP COLUMN email;
+ALTER TABLE users DROP COLUMN username;
+ALTER TABLE users DROP COLUMN phone;
@@ -0,0 +1,113 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver finds the address of the client behind a request. Forwarding
+// headers are only believed when the request came from a trusted proxy,
+// anyone else could send them to pick the address they're counted under
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// Trusted proxies are given as CIDRs or single addresses, none means the
+// direct peer is always the client
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+
+		r.trusted = append(r.trusted, network)
+	}
+
+	return r, nil
+}
+
+// IP of the client. X-Forwarded-For is read from the right, each trusted
+// proxy appends the address it got the request from, so the first untrusted
+// one is the client
+func (r *Resolver) IP(req *http.Request) string {
+	peer := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if !r.isTrusted(peer) {
+		return peer
+	}
+
+	client := peer
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		client = hop
+		if !r.isTrusted(hop) {
+			break
+		}
+	}
+
+	return client
+}
+
+type contextKey struct{}
+
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// The client IP stored by WithIP, empty when there's none
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(contextKey{}).(string)
+	return ip
+}
+
+// Helper
+
+func (r *Resolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
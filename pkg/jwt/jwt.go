@@ -1,46 +1,110 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secretKey = []byte("your_secret_key") // Change this to a secure key
+// Access tokens are short lived, clients renew them with a refresh token
+const AccessTokenTTL = 15 * time.Minute
 
-type jwtUserDataClaims struct {
+type Claims struct {
 	jwt.RegisteredClaims
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 }
 
-func CreateToken(id int, username string) (string, error) {
-	claims := jwtUserDataClaims{
+// Manager signs tokens with the active key and verifies them with any of its
+// keys, picked by the kid header. Rotating means adding a new key as active
+// while keeping the previous one until the tokens it signed have expired
+type Manager struct {
+	active Key
+	keys   map[string]Key
+}
+
+func NewManager(activeKeyID string, keys ...Key) (*Manager, error) {
+	manager := &Manager{keys: map[string]Key{}}
+
+	for _, key := range keys {
+		if _, ok := manager.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		manager.keys[key.ID] = key
+	}
+
+	active, ok := manager.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found", activeKeyID)
+	}
+	manager.active = active
+
+	return manager, nil
+}
+
+// Every token gets a unique ID (jti) so it can be revoked before it expires
+func (m *Manager) CreateToken(id int, username string, role string) (string, error) {
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        hex.EncodeToString(tokenID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 		},
 		UserID:   id,
 		Username: username,
+		Role:     role,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	token := jwt.NewWithClaims(m.active.method, claims)
+	token.Header["kid"] = m.active.ID
+	return token.SignedString(m.active.signKey)
 }
 
-func ValidateToken(tokenString string) (*jwtUserDataClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwtUserDataClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
+func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		key := m.active
+
+		// Tokens issued before key IDs were added carry no kid
+		if kid, ok := token.Header["kid"].(string); ok {
+			key, ok = m.keys[kid]
+			if !ok {
+				return nil, errors.New("unknown key id")
+			}
+		}
+
+		// The algorithm must be the one of the key, never the one the token claims
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return key.verifyKey, nil
 	})
 
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
 
-	claims, ok := token.Claims.(*jwtUserDataClaims)
+	claims, ok := token.Claims.(*Claims)
 	if !ok {
 		return nil, errors.New("could not parse claims")
 	}
 
+	// Tokens issued before they had an ID and an issue time can't be revoked,
+	// their holders have to sign in again
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, errors.New("token can't be revoked")
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a signing key identified by the kid header of the tokens it signs
+type Key struct {
+	ID string
+
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) == 0 {
+		return Key{}, errors.New("empty jwt secret")
+	}
+
+	return Key{ID: id, method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+func NewRSAKey(id string, keyPEM []byte) (Key, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return Key{}, fmt.Errorf("parse rsa private key: %w", err)
+	}
+
+	return Key{ID: id, method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+}
+
+func NewEd25519Key(id string, keyPEM []byte) (Key, error) {
+	key, err := jwt.ParseEdPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return Key{}, fmt.Errorf("parse ed25519 private key: %w", err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return Key{}, errors.New("not an ed25519 private key")
+	}
+
+	return Key{ID: id, method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: private.Public()}, nil
+}
+
+// Builds an asymmetric key for the algorithm from a PEM encoded private key
+func NewKeyFromPEM(algorithm, id string, keyPEM []byte) (Key, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return NewRSAKey(id, keyPEM)
+	case AlgorithmEdDSA:
+		return NewEd25519Key(id, keyPEM)
+	default:
+		return Key{}, fmt.Errorf("unsupported jwt algorithm %q", algorithm)
+	}
+}
+
+// Public part of the key, nil for HMAC keys which have none
+func (k Key) PublicKey() crypto.PublicKey {
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return public
+	default:
+		return nil
+	}
+}
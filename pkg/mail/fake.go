@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeMailer keeps sent messages in memory, used for local development and tests
+type FakeMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewFakeMailer() *FakeMailer {
+	return &FakeMailer{}
+}
+
+func (f *FakeMailer) Send(_ context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func (f *FakeMailer) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Message(nil), f.sent...)
+}
@@ -0,0 +1,38 @@
+package moderation
+
+import "context"
+
+type Verdict int
+
+const (
+	VerdictApprove Verdict = iota + 1
+	VerdictReject
+	// Undecided, a person has to look at the photo
+	VerdictReview
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictApprove:
+		return "approve"
+	case VerdictReject:
+		return "reject"
+	case VerdictReview:
+		return "review"
+	default:
+		return "unknown"
+	}
+}
+
+type Result struct {
+	Verdict Verdict
+
+	// Why the photo was rejected or sent to review, empty when approved
+	Reason string
+}
+
+// Moderator decides whether a photo can be shown to other users. The photo is
+// a JPEG that's already been re-encoded, so it carries no metadata
+type Moderator interface {
+	Moderate(ctx context.Context, photo []byte) (Result, error)
+}
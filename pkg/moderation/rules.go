@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"math"
+)
+
+// RuleModerator runs local checks that need no external service. It's a
+// pre-filter in front of the reviewers: it rejects photos that can't be of a
+// person and flags everything else for a reviewer, it never approves
+type RuleModerator struct {
+	// Shortest side in pixels, smaller photos are rejected
+	MinSide int
+
+	// Longest side over the shortest one, wider photos are flagged as such
+	// for the reviewer
+	MaxAspectRatio float64
+
+	// Standard deviation of the brightness, flatter photos are rejected as blank
+	MinContrast float64
+}
+
+func NewRuleModerator() *RuleModerator {
+	return &RuleModerator{
+		MinSide:        200,
+		MaxAspectRatio: 3,
+		MinContrast:    4,
+	}
+}
+
+func (r *RuleModerator) Moderate(_ context.Context, photo []byte) (Result, error) {
+	img, _, err := image.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return Result{Verdict: VerdictReject, Reason: "photo can't be decoded"}, nil
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	shortest, longest := min(w, h), max(w, h)
+
+	if shortest < r.MinSide {
+		return Result{Verdict: VerdictReject, Reason: fmt.Sprintf("photo is smaller than %dpx", r.MinSide)}, nil
+	}
+
+	if contrast(img) < r.MinContrast {
+		return Result{Verdict: VerdictReject, Reason: "photo is blank"}, nil
+	}
+
+	if float64(longest)/float64(shortest) > r.MaxAspectRatio {
+		return Result{Verdict: VerdictReview, Reason: "unusual aspect ratio"}, nil
+	}
+
+	// Passing says nothing about what's in the photo
+	return Result{Verdict: VerdictReview, Reason: "passed the automatic checks"}, nil
+}
+
+// Standard deviation of the brightness of every pixel
+func contrast(img image.Image) float64 {
+	bounds := img.Bounds()
+
+	var sum, sumSquares float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			sum += gray
+			sumSquares += gray * gray
+		}
+	}
+
+	n := float64(bounds.Dx() * bounds.Dy())
+	mean := sum / n
+
+	return math.Sqrt(max(0, sumSquares/n-mean*mean))
+}
@@ -0,0 +1,192 @@
+//go:build oidcfake
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const fakeKeyID = "fake"
+
+// FakeIssuer is a local OpenID provider for development and tests. Its
+// login page signs in right away as the login_hint email, or
+// fake-user@example.com without one, and redirects back with a code. It's
+// only built with -tags oidcfake so release builds can't sign anyone in
+type FakeIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]fakeCode
+}
+
+type fakeCode struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	expiresAt   time.Time
+}
+
+func NewFakeIssuer(clientID string) (*FakeIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FakeIssuer{
+		key:      key,
+		clientID: clientID,
+		codes:    map[string]fakeCode{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", f.handleDiscovery)
+	mux.HandleFunc("/authorize", f.handleAuthorize)
+	mux.HandleFunc("/token", f.handleToken)
+	mux.HandleFunc("/jwks", f.handleJWKS)
+	f.server = httptest.NewServer(mux)
+
+	return f, nil
+}
+
+func (f *FakeIssuer) URL() string {
+	return f.server.URL
+}
+
+func (f *FakeIssuer) Close() {
+	f.server.Close()
+}
+
+func (f *FakeIssuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, discovery{
+		Issuer:                f.URL(),
+		AuthorizationEndpoint: f.URL() + "/authorize",
+		TokenEndpoint:         f.URL() + "/token",
+		JWKSURI:               f.URL() + "/jwks",
+	})
+}
+
+func (f *FakeIssuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("client_id") != f.clientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	email := query.Get("login_hint")
+	if email == "" {
+		email = "fake-user@example.com"
+	}
+
+	code, err := RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f.mu.Lock()
+	f.codes[code] = fakeCode{
+		redirectURI: redirectURI.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		email:       email,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	f.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (f *FakeIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	f.mu.Lock()
+	code, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) ||
+		r.PostForm.Get("client_id") != f.clientID ||
+		r.PostForm.Get("redirect_uri") != code.redirectURI ||
+		CodeChallenge(r.PostForm.Get("code_verifier")) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.URL(),
+			Subject:   "fake|" + code.email,
+			Audience:  jwt.ClaimStrings{f.clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         code.nonce,
+		Email:         code.email,
+		EmailVerified: true,
+		Name:          "Fake User",
+	})
+	token.Header["kid"] = fakeKeyID
+
+	idToken, err := token.SignedString(f.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "fake-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (f *FakeIssuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	public := f.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string][]jwk{
+		"keys": {{
+			KeyType: "RSA",
+			KeyID:   fakeKeyID,
+			Use:     "sig",
+			N:       base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,150 @@
+package photo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+const (
+	// Decoding is refused above this many pixels, a small file can still
+	// claim huge dimensions. Processing holds about 8 bytes per pixel, so
+	// around 160MB at the limit
+	MaxPixels = 20_000_000
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("photo: only JPEG and PNG are supported")
+	ErrInvalidImage    = errors.New("photo: invalid image")
+	ErrTooManyPixels   = errors.New("photo: image dimensions too large")
+)
+
+// A size every upload is stored in, the longest side is scaled down to MaxSide
+type Variant struct {
+	Name    string
+	MaxSide int
+}
+
+var Variants = []Variant{
+	{Name: "original", MaxSide: 2048},
+	{Name: "large", MaxSide: 1080},
+	{Name: "medium", MaxSide: 640},
+	{Name: "small", MaxSide: 240},
+}
+
+// Processed holds a JPEG per variant, re-encoding drops the EXIF and any other
+// metadata of the upload. Width and Height are of the original variant
+type Processed struct {
+	Width    int
+	Height   int
+	Variants map[string][]byte
+}
+
+// Content type from the first bytes, the client's claim isn't trusted
+func SniffType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+func Process(data []byte) (*Processed, error) {
+	contentType := SniffType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	// JPEG has no transparency, transparent PNG pixels end up white
+	src := image.NewRGBA(image.Rect(0, 0, decoded.Bounds().Dx(), decoded.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), decoded, decoded.Bounds().Min, draw.Over)
+
+	// The orientation lives in the EXIF that's about to be dropped
+	if contentType == "image/jpeg" {
+		src = orient(src, exifOrientation(data))
+	}
+
+	processed := &Processed{Variants: map[string][]byte{}}
+	current := src
+
+	// Largest first so every variant is scaled from the previous one
+	for _, variant := range Variants {
+		current = resize(current, variant.MaxSide)
+
+		if variant.Name == Variants[0].Name {
+			processed.Width = current.Bounds().Dx()
+			processed.Height = current.Bounds().Dy()
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, current, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		processed.Variants[variant.Name] = buf.Bytes()
+	}
+
+	return processed, nil
+}
+
+// Box filter, every destination pixel averages the source pixels it covers
+func resize(src *image.RGBA, maxSide int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	dw, dh := maxSide, maxSide
+	if w > h {
+		dh = max(1, h*maxSide/w)
+	} else {
+		dw = max(1, w*maxSide/h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,41 @@
+package push
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeProvider keeps sent messages in memory, used for local development and tests
+type FakeProvider struct {
+	mu   sync.Mutex
+	sent []Message
+
+	// Optional hook to simulate provider failures
+	Fail func(msg Message) error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+func (f *FakeProvider) Send(_ context.Context, msg Message) error {
+	if f.Fail != nil {
+		if err := f.Fail(msg); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func (f *FakeProvider) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Message(nil), f.sent...)
+}
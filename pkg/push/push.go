@@ -0,0 +1,21 @@
+package push
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken is returned when the provider reports the device token is
+// no longer registered, the device should be removed instead of retried
+var ErrInvalidToken = errors.New("push: invalid device token")
+
+type Message struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+type Provider interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// At most Limit requests in any Window long period
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// Reset is how long until the oldest counted request leaves the window
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, policy Policy, key string) (Result, error)
+}
+
+// Parse a policy written as "<limit>/<window>", e.g. "10/1m"
+func ParsePolicy(name, value string) (Policy, error) {
+	limit, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %s: expected <limit>/<window>, got %q", name, value)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %s: invalid limit %q", name, limit)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %s: invalid window %q", name, window)
+	}
+
+	return Policy{Name: name, Limit: n, Window: d}, nil
+}
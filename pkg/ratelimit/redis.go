@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Sliding window log, every allowed request is kept in a sorted set scored
+// by its time in milliseconds. Rejected requests aren't added so a client
+// over the limit gets through again as soon as the window moves on
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+local count = redis.call('ZCARD', key)
+local allowed = 0
+
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, count, reset}
+`)
+
+type RedisLimiter struct {
+	rdb *redis.Client
+}
+
+func NewRedisLimiter(rdb *redis.Client) *RedisLimiter {
+	return &RedisLimiter{rdb: rdb}
+}
+
+func (l *RedisLimiter) Allow(_ context.Context, policy Policy, key string) (Result, error) {
+	member, err := newMember()
+	if err != nil {
+		return Result{}, err
+	}
+
+	reply, err := slidingWindow.Run(l.rdb, []string{key}, time.Now().UnixMilli(), policy.Window.Milliseconds(), policy.Limit, member).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	values := reply.([]interface{})
+	count := int(values[1].(int64))
+
+	return Result{
+		Allowed:   values[0].(int64) == 1,
+		Limit:     policy.Limit,
+		Remaining: max(policy.Limit-count, 0),
+		Reset:     time.Duration(values[2].(int64)) * time.Millisecond,
+	}, nil
+}
+
+// Requests in the same millisecond need distinct members
+func newMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,32 @@
+package sms
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeSender keeps sent messages in memory, used for local development and tests
+type FakeSender struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+func (f *FakeSender) Send(_ context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func (f *FakeSender) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Message(nil), f.sent...)
+}
@@ -0,0 +1,13 @@
+package sms
+
+import "context"
+
+type Message struct {
+	// Phone number in E.164 format
+	To   string
+	Body string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,60 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioEndpoint = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+
+// TwilioSender sends through the Twilio Messages API, authenticating with the
+// account SID and auth token
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+func NewTwilioSender(accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *TwilioSender) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"To":   {msg.To},
+		"From": {t.from},
+		"Body": {msg.Body},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(twilioEndpoint, t.accountSID), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	return fmt.Errorf("twilio: unexpected status %d: %s", resp.StatusCode, respBody)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FilesystemStore keeps objects as files under a directory, the server serves
+// the directory at publicURL
+type FilesystemStore struct {
+	dir       string
+	publicURL string
+}
+
+func NewFilesystemStore(dir, publicURL string) *FilesystemStore {
+	return &FilesystemStore{
+		dir:       dir,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+func (f *FilesystemStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Written aside then renamed so a reader never sees half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FilesystemStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+func (f *FilesystemStore) Delete(ctx context.Context, key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (f *FilesystemStore) URL(key string) string {
+	return f.publicURL + "/" + key
+}
+
+// Keys can't climb out of the directory
+func (f *FilesystemStore) path(key string) (string, error) {
+	if !fs.ValidPath(key) {
+		return "", errors.New("storage: invalid key " + key)
+	}
+
+	return filepath.Join(f.dir, filepath.FromSlash(key)), nil
+}
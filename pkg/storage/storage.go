@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects under slash separated keys, e.g.
+// photos/1/abc/small.jpg
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+
+	// ErrNotFound when the key doesn't exist
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Deleting a missing key isn't an error
+	Delete(ctx context.Context, key string) error
+
+	// Public URL the object is served from
+	URL(key string) string
+}
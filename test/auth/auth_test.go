@@ -3,19 +3,36 @@ package auth_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/middleware"
+	"github.com/ghaniswara/dating-app/pkg/clientip"
 	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/ghaniswara/dating-app/pkg/mail"
+	"github.com/ghaniswara/dating-app/pkg/ratelimit"
+	"github.com/ghaniswara/dating-app/pkg/totp"
 	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/labstack/echo"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var globalResources *helper_test.TestServerResources
+
 func TestMain(m *testing.M) {
 	// Set up the test server
 	resources, err := helper_test.SetupTestServer(context.TODO())
@@ -26,6 +43,7 @@ func TestMain(m *testing.M) {
 		code = 1
 	} else {
 		// Run tests
+		globalResources = resources
 		code = m.Run()
 	}
 
@@ -117,3 +135,857 @@ func TestSignIn(t *testing.T) {
 
 	assert.NotEmpty(t, response.Data.Token)
 }
+
+// Emails are stored lowercase, signing in or up with another case finds the
+// same account
+func TestEmailCase(t *testing.T) {
+	signUp, err := helper_test.SignUpUser(t, "casedUser", "password123", "Cased@Example.com")
+	if err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+	assert.Equal(t, "cased@example.com", signUp.Email)
+
+	_, err = helper_test.SignInUser(t, "CASED@example.com", "", "password123")
+	assert.NoError(t, err)
+
+	resp := jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/sign-up", "", entity.CreateUserRequest{
+		Name:     "testname",
+		Username: "casedUser2",
+		Password: "password123",
+		Email:    "cased@EXAMPLE.com",
+	})
+	defer resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+	var count int64
+	globalResources.ORM.Model(&entity.AuthCredential{}).Where("LOWER(email) = ?", "cased@example.com").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// A refresh token can be used once, presenting it again revokes the token
+// that replaced it as well
+func TestRefreshTokenRotation(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "refresh@example.com",
+		Username: "refreshuser",
+		Password: "password123",
+	}
+
+	if _, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	body, _ := json.Marshal(reqBody)
+	resp, err := http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	signIn := decodeSignIn(t, resp)
+	assert.NotEmpty(t, signIn.RefreshToken)
+
+	resp = refreshRequest(t, signIn.RefreshToken)
+	rotated := decodeSignIn(t, resp)
+	assert.NotEmpty(t, rotated.Token)
+	assert.NotEqual(t, signIn.RefreshToken, rotated.RefreshToken)
+
+	// Reusing the first token is refused and revokes the rotated one
+	resp = refreshRequest(t, signIn.RefreshToken)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = refreshRequest(t, rotated.RefreshToken)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func refreshRequest(t *testing.T, refreshToken string) *http.Response {
+	body, _ := json.Marshal(entity.RefreshTokenRequest{RefreshToken: refreshToken})
+
+	resp, err := http.Post("http://localhost:8080/v1/auth/refresh", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return resp
+}
+
+func decodeSignIn(t *testing.T, resp *http.Response) entity.SignInResponse {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	response := http_util.HTTPResponse[entity.SignInResponse]{}
+	response, err = http_util.DecodeBody(bodyBytes, response)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	return response.Data
+}
+
+// A logged out access token is rejected right away, logging out from all
+// devices also rejects the other sessions and their refresh tokens
+func TestLogout(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "logout@example.com",
+		Username: "logoutuser",
+		Password: "password123",
+	}
+
+	if _, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	body, _ := json.Marshal(reqBody)
+	signIn := func() entity.SignInResponse {
+		resp, err := http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return decodeSignIn(t, resp)
+	}
+
+	session := signIn()
+
+	resp := authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/logout", session.Token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authorizedRequest(t, http.MethodGet, "http://localhost:8080/v1/blocks", session.Token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	first := signIn()
+	second := signIn()
+
+	resp = authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/logout-all", first.Token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authorizedRequest(t, http.MethodGet, "http://localhost:8080/v1/blocks", second.Token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = refreshRequest(t, second.RefreshToken)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func authorizedRequest(t *testing.T, method, url, token string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return resp
+}
+
+// The verification token works once and marks the email verified, resending
+// is refused afterwards
+func TestVerifyEmail(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "verify@example.com",
+		Username: "verifyuser",
+		Password: "password123",
+	}
+
+	user, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email)
+	if err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	token, err := helper_test.SignInUser(t, reqBody.Email, reqBody.Username, reqBody.Password)
+	if err != nil {
+		t.Fatalf("Failed to Sign In: %v", err)
+	}
+
+	// Signing up sends the verification link
+	rawToken := mailedToken(t, reqBody.Email, "Verify your email")
+
+	body, _ := json.Marshal(entity.VerifyEmailRequest{Token: rawToken})
+
+	resp, err := http.Post("http://localhost:8080/v1/auth/verify-email", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var verified entity.User
+	globalResources.ORM.Where("id = ?", user.ID).First(&verified)
+	assert.NotNil(t, verified.EmailVerifiedAt)
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/verify-email", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/verify-email/resend", token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+// Token of the last link mailed to the address with the subject, mails can be
+// sent after the request returns so it waits for one
+func mailedToken(t *testing.T, to, subject string) string {
+	var messages []mail.Message
+
+	assert.Eventually(t, func() bool {
+		messages = sentMail(to, subject)
+		return len(messages) > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	if len(messages) == 0 {
+		t.Fatalf("No %q mail sent to %s", subject, to)
+	}
+
+	start := strings.Index(messages[len(messages)-1].Body, "token=")
+	if start == -1 {
+		t.Fatalf("No token in the %q mail", subject)
+	}
+
+	link := strings.Fields(messages[len(messages)-1].Body[start:])[0]
+	token, err := url.QueryUnescape(strings.TrimPrefix(link, "token="))
+	if err != nil {
+		t.Fatalf("Failed to read token: %v", err)
+	}
+
+	return token
+}
+
+func sentMail(to, subject string) []mail.Message {
+	var messages []mail.Message
+
+	for _, msg := range globalResources.Server.Mailer().(*mail.FakeMailer).Sent() {
+		if msg.To == to && msg.Subject == subject {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+func TestResendVerificationLimit(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "resend@example.com",
+		Username: "resenduser",
+		Password: "password123",
+	}
+
+	if _, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	token, err := helper_test.SignInUser(t, reqBody.Email, reqBody.Username, reqBody.Password)
+	if err != nil {
+		t.Fatalf("Failed to Sign In: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp := authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/verify-email/resend", token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp := authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/verify-email/resend", token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+// Resetting the password signs out every session and the reset token works
+// once, forgetting an unknown email looks the same as a known one
+func TestPasswordReset(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "reset@example.com",
+		Username: "resetuser",
+		Password: "password123",
+	}
+
+	if _, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	body, _ := json.Marshal(reqBody)
+	resp, err := http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	session := decodeSignIn(t, resp)
+
+	for _, email := range []string{reqBody.Email, "unknown@example.com"} {
+		forgotBody, _ := json.Marshal(entity.ForgotPasswordRequest{Email: email})
+		resp, err := http.Post("http://localhost:8080/v1/auth/password/forgot", "application/json", bytes.NewBuffer(forgotBody))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	rawToken := mailedToken(t, reqBody.Email, "Reset your password")
+	assert.Empty(t, sentMail("unknown@example.com", "Reset your password"))
+
+	resetBody, _ := json.Marshal(entity.ResetPasswordRequest{Token: rawToken, Password: "newpassword123"})
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/password/reset", "application/json", bytes.NewBuffer(resetBody))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/password/reset", "application/json", bytes.NewBuffer(resetBody))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = authorizedRequest(t, http.MethodGet, "http://localhost:8080/v1/blocks", session.Token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = refreshRequest(t, session.RefreshToken)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Revocation has second precision, a sign-in in the same second is revoked too
+	time.Sleep(time.Second)
+
+	_, err = helper_test.SignInUser(t, reqBody.Email, reqBody.Username, "newpassword123")
+	assert.NoError(t, err)
+}
+
+// A hash salted with the email still signs in and is replaced by one which
+// doesn't depend on the email
+func TestLegacyPasswordRehash(t *testing.T) {
+	email := "legacy@example.com"
+	password := "password123"
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password+email), 12)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	user := entity.User{Name: "legacy"}
+	if err := globalResources.ORM.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	changedAt := time.Now()
+
+	err = globalResources.ORM.Create(&entity.AuthCredential{
+		UserID:            user.ID,
+		Email:             email,
+		Username:          "legacyuser",
+		PasswordHash:      string(hashed),
+		PasswordAlgorithm: entity.PasswordBcryptEmail,
+		PasswordCost:      12,
+		PasswordChangedAt: &changedAt,
+	}).Error
+	if err != nil {
+		t.Fatalf("Failed to create credential: %v", err)
+	}
+
+	_, err = helper_test.SignInUser(t, email, "legacyuser", password)
+	assert.NoError(t, err)
+
+	var rehashed entity.AuthCredential
+	globalResources.ORM.Where("user_id = ?", user.ID).First(&rehashed)
+	assert.Equal(t, entity.PasswordBcrypt, rehashed.PasswordAlgorithm)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(rehashed.PasswordHash), []byte(password)))
+	assert.NotNil(t, rehashed.LastUsedAt)
+}
+
+// Changing the password signs out every session, changing the email keeps
+// the password working and asks for the new email to be verified
+func TestChangePasswordAndEmail(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "change@example.com",
+		Username: "changeuser",
+		Password: "password123",
+	}
+
+	user, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email)
+	if err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	token, err := helper_test.SignInUser(t, reqBody.Email, reqBody.Username, reqBody.Password)
+	if err != nil {
+		t.Fatalf("Failed to Sign In: %v", err)
+	}
+
+	resp := jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/password/change", token,
+		entity.ChangePasswordRequest{CurrentPassword: "wrongpassword", NewPassword: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/password/change", token,
+		entity.ChangePasswordRequest{CurrentPassword: reqBody.Password, NewPassword: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authorizedRequest(t, http.MethodGet, "http://localhost:8080/v1/blocks", token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Revocation has second precision, a sign-in in the same second is revoked too
+	time.Sleep(time.Second)
+
+	token, err = helper_test.SignInUser(t, reqBody.Email, reqBody.Username, "newpassword123")
+	if err != nil {
+		t.Fatalf("Failed to Sign In: %v", err)
+	}
+
+	if _, err := helper_test.SignUpUser(t, "takenuser", "password123", "taken@example.com"); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	// Emails are compared and stored ignoring case
+	resp = jsonRequest(t, http.MethodPut, "http://localhost:8080/v1/account/email", token,
+		entity.ChangeEmailRequest{Email: "Taken@Example.com", Password: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = jsonRequest(t, http.MethodPut, "http://localhost:8080/v1/account/email", token,
+		entity.ChangeEmailRequest{Email: "not-an-email", Password: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = jsonRequest(t, http.MethodPut, "http://localhost:8080/v1/account/email", token,
+		entity.ChangeEmailRequest{Email: "Changed@Example.com", Password: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var changed entity.User
+	globalResources.ORM.Where("id = ?", user.ID).First(&changed)
+	assert.Equal(t, "changed@example.com", changed.Email)
+	assert.Nil(t, changed.EmailVerifiedAt)
+
+	_, err = helper_test.SignInUser(t, "changed@example.com", reqBody.Username, "newpassword123")
+	assert.NoError(t, err)
+
+	// With the wrong password change above that's 5 wrong passwords, the check
+	// is then locked even for the right one
+	for i := 0; i < 4; i++ {
+		resp = jsonRequest(t, http.MethodPut, "http://localhost:8080/v1/account/email", token,
+			entity.ChangeEmailRequest{Email: "other@example.com", Password: "wrongpassword"})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = jsonRequest(t, http.MethodPut, "http://localhost:8080/v1/account/email", token,
+		entity.ChangeEmailRequest{Email: "other@example.com", Password: "newpassword123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func jsonRequest(t *testing.T, method, url, token string, body any) *http.Response {
+	payload, _ := json.Marshal(body)
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return resp
+}
+
+// Wrong passwords ask for a CAPTCHA after 3 failures and lock the account
+// after 5, the right password is refused while it's locked
+func TestSignInLockout(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "lockout@example.com",
+		Username: "lockoutuser",
+		Password: "password123",
+	}
+
+	user, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email)
+	if err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	signInFrom := func(ip, password string) (*http.Response, entity.SignInFailureResponse) {
+		body, _ := json.Marshal(entity.SignInRequest{Email: reqBody.Email, Password: password})
+
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/v1/auth/sign-in", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ip != "" {
+			req.Header.Set("X-Forwarded-For", ip)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		response, _ := http_util.DecodeBody(bodyBytes, http_util.HTTPResponse[entity.SignInFailureResponse]{})
+
+		return resp, response.Data
+	}
+
+	signIn := func(password string) (*http.Response, entity.SignInFailureResponse) {
+		return signInFrom("", password)
+	}
+
+	for i := 1; i < 5; i++ {
+		resp, failure := signIn("wrongpassword")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		if i >= 3 {
+			assert.True(t, failure.CaptchaRequired)
+		}
+	}
+
+	resp, failure := signIn("wrongpassword")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	assert.Equal(t, 60, failure.RetryAfter)
+
+	resp, _ = signIn(reqBody.Password)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// The owner signing in from elsewhere isn't locked out
+	resp, _ = signInFrom("203.0.113.7", reqBody.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var failures int64
+	globalResources.ORM.Model(&entity.SecurityAuditLog{}).
+		Where("user_id = ? AND event = ?", user.ID, entity.SecurityEventSignInFailed).
+		Count(&failures)
+	assert.Equal(t, int64(5), failures)
+}
+
+// Requests over the limit are refused until the window moves past the
+// oldest one, responses carry the RateLimit headers
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewRedisLimiter(globalResources.Redis)
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Window: time.Second}
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(context.Background(), policy, ":ratelimit:test")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1-i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(context.Background(), policy, ":ratelimit:test")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.Reset, time.Duration(0))
+
+	time.Sleep(result.Reset + 10*time.Millisecond)
+
+	result, err = limiter.Allow(context.Background(), policy, ":ratelimit:test")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	body, _ := json.Marshal(entity.SignInRequest{Email: "ratelimit@example.com", Password: "password123"})
+	resp, err := http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Limit"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"))
+
+	// Over the limit the middleware refuses with 429, a client behind an
+	// untrusted peer can't dodge it by sending its own X-Forwarded-For
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(middleware.ClientIPMiddleware(resolver))
+	e.GET("/limited", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, middleware.NewRateLimiter(limiter, map[string]ratelimit.Policy{
+		"limited": {Name: "limited-" + strconv.FormatInt(time.Now().UnixNano(), 10), Limit: 2, Window: time.Minute},
+	}).Limit("limited"))
+
+	limited := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusNoContent, limited("198.51.100.1:1234", "").Code)
+	}
+
+	rec := limited("198.51.100.1:1234", "203.0.113.99")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	limitedBody, err := http_util.DecodeBody[http_util.HTTPErrorResponse[any]](rec.Body.Bytes(), http_util.HTTPErrorResponse[any]{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Too many requests", limitedBody.Message)
+	assert.Equal(t, []http_util.ErrorResponse{{Property: "request", Detail: "rate limit exceeded, try again later"}}, limitedBody.Errors)
+
+	// Behind a trusted proxy the forwarded client is counted on its own
+	assert.Equal(t, http.StatusNoContent, limited("10.0.0.2:1234", "203.0.113.99").Code)
+	assert.Equal(t, http.StatusTooManyRequests, limited("10.0.0.2:1234", "198.51.100.1").Code)
+}
+
+// With TOTP enabled sign-in returns a challenge, a code only works once and
+// recovery codes can stand in for the authenticator
+func TestTOTP(t *testing.T) {
+	reqBody := entity.SignInRequest{
+		Email:    "totp@example.com",
+		Username: "totpuser",
+		Password: "password123",
+	}
+
+	if _, err := helper_test.SignUpUser(t, reqBody.Username, reqBody.Password, reqBody.Email); err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	token, err := helper_test.SignInUser(t, reqBody.Email, reqBody.Username, reqBody.Password)
+	if err != nil {
+		t.Fatalf("Failed to Sign In: %v", err)
+	}
+
+	resp := authorizedRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/totp", token)
+	enrollment := decodeData[entity.TOTPEnrollmentResponse](t, resp)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/totp/confirm", token, entity.MFACodeRequest{Code: code})
+	recovery := decodeData[entity.RecoveryCodesResponse](t, resp)
+	assert.Len(t, recovery.RecoveryCodes, 10)
+
+	body, _ := json.Marshal(reqBody)
+	signIn := func() entity.SignInResponse {
+		resp, err := http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return decodeSignIn(t, resp)
+	}
+
+	challenge := signIn()
+	assert.True(t, challenge.MFARequired)
+	assert.Empty(t, challenge.Token)
+
+	// The code confirming the enrollment was already used
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/challenge", "",
+		entity.MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/challenge", "",
+		entity.MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[0]})
+	tokens := decodeSignIn(t, resp)
+	assert.NotEmpty(t, tokens.Token)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/challenge", "",
+		entity.MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[1]})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	challenge = signIn()
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/challenge", "",
+		entity.MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[0]})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The confirmation and the challenges used up the user's 5 codes, a new
+	// challenge or a signed in session can't guess on
+	challenge = signIn()
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/challenge", "",
+		entity.MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[2]})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	resp = jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/mfa/totp/disable", tokens.Token, entity.MFACodeRequest{Code: recovery.RecoveryCodes[2]})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func decodeData[T any](t *testing.T, resp *http.Response) T {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	response, err := http_util.DecodeBody(bodyBytes, http_util.HTTPResponse[T]{})
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	return response.Data
+}
+
+// Signing in with the fake provider creates a user once and links the
+// identity to an existing user with the same email
+func TestOIDC(t *testing.T) {
+	start := func(email string) *http.Response {
+		// The start redirects to the fake issuer which redirects back to the callback
+		resp, err := http.Get("http://localhost:8080/v1/auth/oidc/fake/start?login_hint=" + email)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+	signIn := func(email string) entity.SignInResponse {
+		return decodeSignIn(t, start(email))
+	}
+
+	first := signIn("oidc@example.com")
+	assert.NotEmpty(t, first.Token)
+
+	second := signIn("oidc@example.com")
+	assert.NotEmpty(t, second.Token)
+
+	var users int64
+	globalResources.ORM.Model(&entity.AuthCredential{}).Where("email = ?", "oidc@example.com").Count(&users)
+	assert.Equal(t, int64(1), users)
+
+	existing, err := helper_test.SignUpUser(t, "oidclinkuser", "password123", "oidclink@example.com")
+	if err != nil {
+		t.Fatalf("Failed to Sign Up: %v", err)
+	}
+
+	// Until the account's email is verified it could belong to someone who
+	// signed up with another person's email, linking would hand it over
+	resp := start("oidclink@example.com")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var identities int64
+	globalResources.ORM.Model(&entity.Identity{}).Where("provider = ? AND email = ?", "fake", "oidclink@example.com").Count(&identities)
+	assert.Equal(t, int64(0), identities)
+
+	globalResources.ORM.Model(&entity.User{}).Where("id = ?", existing.ID).Update("email_verified_at", time.Now())
+	globalResources.Redis.Del(fmt.Sprintf(":user:%d:record:v2", existing.ID))
+
+	signIn("oidclink@example.com")
+
+	var identity entity.Identity
+	globalResources.ORM.Where("provider = ? AND email = ?", "fake", "oidclink@example.com").First(&identity)
+	assert.Equal(t, uint(existing.ID), identity.UserID)
+
+	resp, err = http.Get("http://localhost:8080/v1/auth/oidc/fake/callback?state=unknown&code=unknown")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get("http://localhost:8080/v1/auth/oidc/unknown/start")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// The texted code signs the phone up on first use and in afterwards, each code
+// works once and is dropped after 5 wrong guesses
+func TestPhoneSignIn(t *testing.T) {
+	phone := "+6281234567890"
+
+	sendCode := func() {
+		resp := jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/phone/otp", "", entity.PhoneOTPRequest{Phone: phone})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// The texted code is only known to the sender, replace it with a known one
+		phoneHash := sha256.Sum256([]byte(phone))
+		codeHash := sha256.Sum256([]byte(phone + ":123456"))
+		globalResources.Redis.Set(":auth:phone-otp:"+hex.EncodeToString(phoneHash[:]), hex.EncodeToString(codeHash[:]), time.Minute)
+	}
+
+	signIn := func(code string) *http.Response {
+		return jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/phone/sign-in", "",
+			entity.PhoneSignInRequest{Phone: phone, Code: code, Name: "Phone User"})
+	}
+
+	resp := jsonRequest(t, http.MethodPost, "http://localhost:8080/v1/auth/phone/otp", "", entity.PhoneOTPRequest{Phone: "081234567890"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	sendCode()
+
+	resp = signIn("000000")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	tokens := decodeSignIn(t, signIn("123456"))
+	assert.NotEmpty(t, tokens.Token)
+
+	resp = signIn("123456")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var credential entity.AuthCredential
+	globalResources.ORM.Where("phone = ?", phone).First(&credential)
+	assert.Empty(t, credential.Email)
+	assert.Empty(t, credential.PasswordHash)
+
+	var user entity.User
+	globalResources.ORM.Where("id = ?", credential.UserID).First(&user)
+	assert.Equal(t, "Phone User", user.Name)
+	assert.NotNil(t, user.PhoneVerifiedAt)
+
+	sendCode()
+	tokens = decodeSignIn(t, signIn("123456"))
+	assert.NotEmpty(t, tokens.Token)
+
+	var users int64
+	globalResources.ORM.Model(&entity.AuthCredential{}).Where("phone = ?", phone).Count(&users)
+	assert.Equal(t, int64(1), users)
+
+	sendCode()
+	for i := 0; i < 5; i++ {
+		resp = signIn("000000")
+		resp.Body.Close()
+	}
+
+	resp = signIn("123456")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,51 @@
+package clientip_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/pkg/clientip"
+	"github.com/stretchr/testify/assert"
+)
+
+// Forwarding headers from an untrusted peer are ignored, behind trusted
+// proxies the rightmost untrusted hop is the client
+func TestResolverIP(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8", "::1", " "})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expected     string
+	}{
+		{"direct", "198.51.100.1:1234", nil, "198.51.100.1"},
+		{"spoofed", "198.51.100.1:1234", []string{"203.0.113.9"}, "198.51.100.1"},
+		{"one proxy", "10.0.0.2:1234", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"spoof behind proxies", "10.0.0.2:1234", []string{"1.2.3.4, 203.0.113.9, 10.0.0.3"}, "203.0.113.9"},
+		{"split headers", "10.0.0.2:1234", []string{"1.2.3.4", "203.0.113.9"}, "203.0.113.9"},
+		{"only proxies", "10.0.0.2:1234", []string{"10.0.0.4, 10.0.0.3"}, "10.0.0.4"},
+		{"garbage hop", "10.0.0.2:1234", []string{"203.0.113.9, nonsense"}, "10.0.0.2"},
+		{"no header", "[::1]:1234", nil, "::1"},
+		{"ipv6 proxy", "[::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			assert.Equal(t, tc.expected, resolver.IP(req))
+		})
+	}
+}
+
+func TestNewResolverInvalid(t *testing.T) {
+	_, err := clientip.NewResolver([]string{"not-an-ip"})
+	assert.Error(t, err)
+
+	_, err = clientip.NewResolver([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
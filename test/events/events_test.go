@@ -0,0 +1,282 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/events"
+	eventUseCase "github.com/ghaniswara/dating-app/internal/usecase/event"
+	notificationUseCase "github.com/ghaniswara/dating-app/internal/usecase/notification"
+	matchWorker "github.com/ghaniswara/dating-app/internal/worker/match"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var globalResources *helper_test.TestServerResources
+
+func TestMain(m *testing.M) {
+	// Set up the test server
+	resources, err := helper_test.SetupTestServer(context.TODO())
+	var code int
+
+	if err != nil {
+		log.Printf("Failed to set up test server: %s", err)
+		code = 1
+	} else {
+		// Run tests
+		globalResources = resources
+		code = m.Run()
+	}
+
+	resources.CleanupTestServer()
+	os.Exit(code)
+}
+
+// Both groups should receive every message, published before or after subscribing
+func TestRedisStreamConsumerGroups(t *testing.T) {
+	topic := "test." + faker.Word()
+	publisher := events.NewRedisStreamPublisher(globalResources.Redis)
+
+	publishMessages(t, publisher, topic, 3)
+
+	received := map[string]chan events.Message{
+		"group-a": make(chan events.Message, 10),
+		"group-b": make(chan events.Message, 10),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for group, ch := range received {
+		subscriber := events.NewRedisStreamSubscriber(globalResources.Redis, events.RedisStreamSubscriberOptions{
+			Consumer: group,
+			Block:    100 * time.Millisecond,
+		})
+
+		go subscriber.Subscribe(ctx, topic, group, func(_ context.Context, msg events.Message) error {
+			ch <- msg
+			return nil
+		})
+	}
+
+	publishMessages(t, publisher, topic, 2)
+
+	for group, ch := range received {
+		assert.Len(t, collect(ch, 5, 5*time.Second), 5, group)
+	}
+}
+
+// A failing handler should see the message MaxDeliveries times before it lands in the dead letter stream
+func TestRedisStreamDeadLetter(t *testing.T) {
+	topic := "test." + faker.Word()
+	publisher := events.NewRedisStreamPublisher(globalResources.Redis)
+
+	publishMessages(t, publisher, topic, 1)
+
+	attempts := make(chan events.Message, 10)
+	dead := make(chan events.Message, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscriber := events.NewRedisStreamSubscriber(globalResources.Redis, events.RedisStreamSubscriberOptions{
+		Block:         100 * time.Millisecond,
+		RetryAfter:    100 * time.Millisecond,
+		MaxDeliveries: 3,
+	})
+
+	go subscriber.Subscribe(ctx, topic, "failing", func(_ context.Context, msg events.Message) error {
+		attempts <- msg
+		return errors.New("handler failed")
+	})
+
+	go subscriber.Subscribe(ctx, events.DeadLetterTopic(topic), "inspector", func(_ context.Context, msg events.Message) error {
+		dead <- msg
+		return nil
+	})
+
+	deliveries := collect(attempts, 3, 10*time.Second)
+	assert.Len(t, deliveries, 3)
+	assert.Equal(t, 3, deliveries[len(deliveries)-1].Deliveries)
+
+	deadLetters := collect(dead, 1, 10*time.Second)
+	assert.Len(t, deadLetters, 1)
+	assert.Equal(t, "0", deadLetters[0].Key)
+}
+
+func TestMemoryBusRetry(t *testing.T) {
+	bus := events.NewMemoryBus()
+	bus.MaxDeliveries = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publishMessages(t, bus, "memory", 2)
+
+	attempts := make(chan events.Message, 10)
+	go bus.Subscribe(ctx, "memory", "group", func(_ context.Context, msg events.Message) error {
+		attempts <- msg
+		if msg.Key == "0" {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	assert.Len(t, collect(attempts, 3, 5*time.Second), 3)
+
+	assert.Eventually(t, func() bool {
+		return len(bus.Messages(events.DeadLetterTopic("memory"))) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// Publishing must not block behind a group that hasn't drained, even from
+// inside a handler publishing to the same bus
+func TestMemoryBusBacklog(t *testing.T) {
+	bus := events.NewMemoryBus()
+	bus.MaxDeliveries = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publishMessages(t, bus, "backlog", 2000)
+
+	handled := make(chan events.Message, 4000)
+	go bus.Subscribe(ctx, "backlog", "group", func(_ context.Context, msg events.Message) error {
+		handled <- msg
+		return errors.New("handler failed")
+	})
+
+	assert.Len(t, collect(handled, 2000, 5*time.Second), 2000)
+	publishMessages(t, bus, "backlog", 1)
+
+	assert.Eventually(t, func() bool {
+		return len(bus.Messages(events.DeadLetterTopic("backlog"))) == 2001
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// A match.created published twice under the same key notifies once, and a
+// retry after a failed step doesn't repeat the steps that went through
+func TestMatchConsumerDeduplicates(t *testing.T) {
+	bus := events.NewMemoryBus()
+	published := &fakeEventCase{}
+	notified := &fakeNotificationCase{failures: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consumer := matchWorker.NewConsumer(bus, events.NewMemoryDeduplicator(), published, notified)
+	go consumer.Run(ctx)
+
+	payload, _ := json.Marshal(entity.MatchCreatedEvent{UserID: 1, MatchedUserID: 2})
+	for i := 0; i < 2; i++ {
+		err := bus.Publish(ctx, events.Message{Key: "42", Topic: entity.TopicMatchCreated, Payload: payload})
+		assert.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return notified.count() == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, notified.count())
+	assert.Equal(t, []int{1, 2}, published.users())
+
+	// The retry queues under the same key, so devices queued by the failed
+	// attempt aren't queued again
+	notified.mu.Lock()
+	keys := notified.keys
+	notified.mu.Unlock()
+	assert.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+}
+
+type fakeEventCase struct {
+	eventUseCase.IEventUseCase
+
+	mu        sync.Mutex
+	published []int
+}
+
+func (f *fakeEventCase) Publish(_ context.Context, userID int, _ entity.EventType, _ interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.published = append(f.published, userID)
+	return nil
+}
+
+func (f *fakeEventCase) users() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]int(nil), f.published...)
+}
+
+type fakeNotificationCase struct {
+	notificationUseCase.INotificationUseCase
+
+	mu       sync.Mutex
+	failures int
+	notified int
+	keys     []string
+}
+
+func (f *fakeNotificationCase) Notify(_ context.Context, _ int, _ entity.NotificationType, _ int, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.keys = append(f.keys, key)
+
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("push queue down")
+	}
+
+	f.notified++
+	return nil
+}
+
+func (f *fakeNotificationCase) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.notified
+}
+
+func publishMessages(t *testing.T, publisher events.Publisher, topic string, count int) {
+	for i := 0; i < count; i++ {
+		err := publisher.Publish(context.TODO(), events.Message{
+			Key:     string(rune('0' + i)),
+			Topic:   topic,
+			Payload: []byte(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("Failed to publish: %s", err)
+		}
+	}
+}
+
+func collect(ch chan events.Message, count int, timeout time.Duration) []events.Message {
+	var messages []events.Message
+	deadline := time.After(timeout)
+
+	for len(messages) < count {
+		select {
+		case msg := <-ch:
+			messages = append(messages, msg)
+		case <-deadline:
+			return messages
+		}
+	}
+
+	return messages
+}
@@ -40,6 +40,7 @@ type TestServerResources struct {
 	Address       string
 	ORM           *gorm.DB
 	Redis         *redis.Client
+	Server        *internal.Server
 }
 
 // setupTestServer sets up the test environment including Docker resources and server
@@ -93,9 +94,17 @@ func SetupTestServer(ctx context.Context) (*TestServerResources, error) {
 		cancel()
 		return nil, err
 	}
-	// Run the server
-	args := []string{"test"}
-	go internal.Run(ctx, os.Stdout, args)
+	// Run the server, tests reach its fakes through the returned resources
+	server, err := internal.NewServer(ctx, os.Stdout, "test")
+
+	if err != nil {
+		pool.Purge(redisResource)
+		pool.Purge(dbResource)
+		cancel()
+		return nil, err
+	}
+
+	go internal.Serve(ctx, os.Stdout, server)
 
 	// Wait for server readiness
 	if !waitForServer(ctx, config.Get("PORT")) {
@@ -113,6 +122,7 @@ func SetupTestServer(ctx context.Context) (*TestServerResources, error) {
 		RedisResource: redisResource,
 		ORM:           gormDB,
 		Redis:         redisClient,
+		Server:        server,
 	}, nil
 }
 
@@ -360,17 +370,56 @@ func SignInUser(t *testing.T, email, username, password string) (token string, e
 	return response.Data.Token, nil
 }
 
+// Populated users have a verified email so they show up in dating profiles
 func PopulateUsers(db *gorm.DB, count int) (users []entity.User, err error) {
 	for i := 0; i < count; i++ {
+		verifiedAt := time.Now()
 		user := entity.User{
-			Name:      faker.Name(),
-			Email:     faker.Email(),
-			Username:  faker.Username(),
-			Password:  faker.Password(),
-			IsPremium: false,
+			Name:            faker.Name(),
+			Email:           faker.Email(),
+			Username:        faker.Username(),
+			IsPremium:       false,
+			EmailVerifiedAt: &verifiedAt,
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return users, err
 		}
-		db.Create(&user)
+
+		// The identifiers are only stored with the credential
+		err := db.Create(&entity.AuthCredential{
+			UserID:   user.ID,
+			Email:    user.Email,
+			Username: user.Username,
+		}).Error
+		if err != nil {
+			return users, err
+		}
+
 		users = append(users, user)
 	}
 	return users, nil
 }
+
+// Sign up a new user with the given role, the role is set in the database
+// before signing in so it ends up in the token claims
+func SignInWithRole(t *testing.T, db *gorm.DB, role entity.Role) (entity.SignUpResponse, string) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user, err := SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	if err := db.Model(&entity.User{}).Where("id = ?", user.ID).Update("role", role).Error; err != nil {
+		t.Fatalf("Failed to set role: %s", err)
+	}
+
+	token, err := SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	return user, token
+}
@@ -0,0 +1,109 @@
+package jwt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/pkg/jwt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tokens signed with the previous key stay valid after rotating to a new
+// active key, until the previous key is removed
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := jwt.NewHMACKey("2024-11", []byte("old_secret"))
+	assert.NoError(t, err)
+
+	newKey, err := jwt.NewHMACKey("2024-12", []byte("new_secret"))
+	assert.NoError(t, err)
+
+	before, err := jwt.NewManager("2024-11", oldKey)
+	assert.NoError(t, err)
+
+	token, err := before.CreateToken(1, "user", "user")
+	assert.NoError(t, err)
+
+	rotated, err := jwt.NewManager("2024-12", oldKey, newKey)
+	assert.NoError(t, err)
+
+	claims, err := rotated.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claims.UserID)
+	assert.NotEmpty(t, claims.ID)
+
+	retired, err := jwt.NewManager("2024-12", newKey)
+	assert.NoError(t, err)
+
+	_, err = retired.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+// Tokens from before revocation have no jti or iat and are refused, they
+// couldn't be revoked otherwise
+func TestLegacyTokenRejected(t *testing.T) {
+	key, err := jwt.NewHMACKey("2024-11", []byte("secret"))
+	assert.NoError(t, err)
+
+	manager, err := jwt.NewManager("2024-11", key)
+	assert.NoError(t, err)
+
+	legacy, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, jwt.Claims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+		},
+		UserID:   1,
+		Username: "user",
+	}).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	_, err = manager.ValidateToken(legacy)
+	assert.Error(t, err)
+}
+
+// Asymmetric keys are published in the JWKS, HMAC keys never are
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER})
+
+	rsaSigner, err := jwt.NewKeyFromPEM(jwt.AlgorithmRS256, "rsa", rsaPEM)
+	assert.NoError(t, err)
+
+	edSigner, err := jwt.NewKeyFromPEM(jwt.AlgorithmEdDSA, "ed", edPEM)
+	assert.NoError(t, err)
+
+	hmacKey, err := jwt.NewHMACKey("hmac", []byte("secret"))
+	assert.NoError(t, err)
+
+	manager, err := jwt.NewManager("ed", rsaSigner, edSigner, hmacKey)
+	assert.NoError(t, err)
+
+	token, err := manager.CreateToken(7, "user", "admin")
+	assert.NoError(t, err)
+
+	claims, err := manager.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", claims.Role)
+
+	jwks := manager.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "ed", jwks.Keys[0].KeyID)
+	assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	assert.Equal(t, "rsa", jwks.Keys[1].KeyID)
+	assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+}
@@ -0,0 +1,361 @@
+package match__test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	exportRepository "github.com/ghaniswara/dating-app/internal/repository/export"
+	photoRepository "github.com/ghaniswara/dating-app/internal/repository/photo"
+	userRepository "github.com/ghaniswara/dating-app/internal/repository/user"
+	exportUseCase "github.com/ghaniswara/dating-app/internal/usecase/export"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/ghaniswara/dating-app/pkg/moderation"
+	"github.com/ghaniswara/dating-app/pkg/storage"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"gotest.tools/assert"
+)
+
+// Suspending a reported user through the moderation queue locks them out of
+// sign-in and rejects the token they already have
+func TestSuspendFromReport(t *testing.T) {
+	reporterName := faker.Username()
+	reporterPassword := faker.Password()
+	reporterEmail := faker.Email()
+
+	if _, err := helper_test.SignUpUser(t, reporterName, reporterPassword, reporterEmail); err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	reporterToken, err := helper_test.SignInUser(t, reporterEmail, reporterName, reporterPassword)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	body, _ := json.Marshal(entity.CreateReportRequest{
+		ReportedUserID: user.ID,
+		Reason:         entity.ReportReasonHarassment,
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/v1/reports", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+reporterToken)
+
+	report := decodeReport(t, req)
+
+	_, moderatorToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+
+	body, _ = json.Marshal(entity.ResolveReportRequest{
+		Action:      entity.ModerationSuspend,
+		Note:        "abusive messages",
+		SuspendDays: 7,
+	})
+
+	req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:8080/v1/admin/reports/%d/action", report.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+	report = decodeReport(t, req)
+	assert.Equal(t, report.Status, entity.ReportStatusActioned.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/v1/match/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+	body, _ = json.Marshal(entity.SignInRequest{
+		Email:    email,
+		Username: username,
+		Password: password,
+	})
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+	// The suspended user no longer shows up in discovery
+	profiles, err := getMatchProfiles(t, reporterToken, nil)
+	if err != nil {
+		t.Fatalf("Failed to get profiles: %s", err)
+	}
+
+	for _, profile := range profiles {
+		assert.Assert(t, int(profile.ID) != user.ID)
+	}
+}
+
+// Deleting the account signs the user out and ends their matches, signing in
+// during the grace period restores it, afterwards the data is erased
+func TestDeleteAccount(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	others, _ := helper_test.PopulateUsers(globalResources.ORM, 1)
+	match := entity.SwipeTransaction{
+		UserID:    uint(user.ID),
+		ToID:      others[0].ID,
+		Date:      time.Now(),
+		Action:    entity.ActionLike,
+		Time:      time.Now(),
+		IsMatched: true,
+	}
+	globalResources.ORM.Create(&match)
+
+	deleteAccount := func(token string) {
+		req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/v1/account", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %s", err)
+		}
+		resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+	}
+
+	deleteAccount(token)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/v1/match/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+
+	globalResources.ORM.First(&match, match.ID)
+	assert.Assert(t, !match.IsMatched)
+
+	// Revocation has second precision, a sign-in in the same second is revoked too
+	time.Sleep(time.Second)
+
+	body, _ := json.Marshal(entity.SignInRequest{Email: email, Username: username, Password: password})
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	var signIn http_util.HTTPResponse[entity.SignInResponse]
+	json.NewDecoder(resp.Body).Decode(&signIn)
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Assert(t, signIn.Data.AccountRestored)
+
+	var restored entity.User
+	globalResources.ORM.First(&restored, user.ID)
+	assert.Equal(t, restored.Status, entity.AccountActive)
+	assert.Assert(t, restored.DeletionScheduledAt == nil)
+
+	deleteAccount(signIn.Data.Token)
+
+	// Skip the grace period
+	globalResources.ORM.Model(&entity.User{}).Where("id = ?", user.ID).Update("deletion_scheduled_at", time.Now().Add(-time.Minute))
+
+	repo := userRepository.New(globalResources.ORM, globalResources.Redis)
+
+	due, err := repo.GetUsersDueForErasure(context.TODO(), time.Now(), 100)
+	if err != nil {
+		t.Fatalf("Failed to get users due for erasure: %s", err)
+	}
+	assert.Assert(t, slices.ContainsFunc(due, func(u entity.User) bool { return int(u.ID) == user.ID }))
+
+	if err := repo.EraseUser(context.TODO(), user.ID); err != nil {
+		t.Fatalf("Failed to erase user: %s", err)
+	}
+
+	erased, err := repo.GetUserByID(context.TODO(), user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get erased user: %s", err)
+	}
+	assert.Equal(t, erased.Email, "")
+	assert.Equal(t, erased.Username, "")
+	assert.Assert(t, erased.ErasedAt != nil)
+
+	var credentials int64
+	globalResources.ORM.Model(&entity.AuthCredential{}).Where("user_id = ?", user.ID).Count(&credentials)
+	assert.Equal(t, credentials, int64(0))
+
+	var swipes int64
+	globalResources.ORM.Model(&entity.SwipeTransaction{}).Where("user_id = ? OR to_id = ?", user.ID, user.ID).Count(&swipes)
+	assert.Equal(t, swipes, int64(0))
+
+	keys, _ := globalResources.Redis.Keys(fmt.Sprintf(":user:%d:*", user.ID)).Result()
+	assert.Equal(t, len(keys), 0)
+
+	resp, err = http.Post("http://localhost:8080/v1/auth/sign-in", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+}
+
+// The export archive holds the user's profile, swipes and matches, it's downloaded
+// through a signed link that stops working once tampered with
+func TestDataExport(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	// A super-like answered with a like is a match without the match flag, a
+	// like that wasn't returned isn't one
+	others, _ := helper_test.PopulateUsers(globalResources.ORM, 2)
+	globalResources.ORM.Create(&[]entity.SwipeTransaction{
+		{UserID: uint(user.ID), ToID: others[0].ID, Date: time.Now(), Action: entity.ActionSuperLike, Time: time.Now()},
+		{UserID: others[0].ID, ToID: uint(user.ID), Date: time.Now(), Action: entity.ActionLike, Time: time.Now()},
+		{UserID: uint(user.ID), ToID: others[1].ID, Date: time.Now(), Action: entity.ActionLike, Time: time.Now()},
+	})
+
+	requestExport := func() (*http.Response, http_util.HTTPResponse[entity.DataExportResponse]) {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/v1/account/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var body http_util.HTTPResponse[entity.DataExportResponse]
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	resp, requested := requestExport()
+	assert.Equal(t, resp.StatusCode, http.StatusAccepted)
+	assert.Equal(t, requested.Data.Status, entity.DataExportPending.String())
+
+	resp, _ = requestExport()
+	assert.Equal(t, resp.StatusCode, http.StatusTooManyRequests)
+
+	// Build it now instead of waiting for the worker
+	exportCase := exportUseCase.New(
+		exportRepository.NewExportRepo(globalResources.ORM),
+		photoUseCase.New(photoRepository.NewPhotoRepo(globalResources.ORM), storage.NewFilesystemStore(globalResources.Config.Get("STORAGE_DIR"), ""), moderation.NewRuleModerator()),
+		globalResources.Config.Get("EXPORT_SIGNING_KEY"),
+		globalResources.Config.Get("APP_URL"),
+	)
+
+	if _, err := exportCase.BuildPending(context.TODO(), 100); err != nil {
+		t.Fatalf("Failed to build exports: %s", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:8080/v1/account/export/%d", requested.Data.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	var polled http_util.HTTPResponse[entity.DataExportResponse]
+	json.NewDecoder(resp.Body).Decode(&polled)
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, polled.Data.Status, entity.DataExportReady.String())
+	assert.Assert(t, polled.Data.DownloadURL != "")
+
+	resp, err = http.Get(strings.Replace(polled.Data.DownloadURL, "signature=", "signature=0", 1))
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+	resp, err = http.Get(polled.Data.DownloadURL)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	archive, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Failed to read archive: %s", err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		f, _ := file.Open()
+		files[file.Name], _ = io.ReadAll(f)
+		f.Close()
+	}
+
+	var profile entity.ExportProfile
+	json.Unmarshal(files["profile.json"], &profile)
+	assert.Equal(t, profile.ID, user.ID)
+	assert.Equal(t, profile.Email, email)
+
+	var matches []entity.ExportMatch
+	json.Unmarshal(files["matches.json"], &matches)
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].UserID, int(others[0].ID))
+}
@@ -0,0 +1,125 @@
+package match__test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"gotest.tools/assert"
+)
+
+// Regular users can't reach the admin routes, moderators can look users up
+// but only admins can change premium and the like quota
+func TestAdminRoles(t *testing.T) {
+	user, userToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleUser)
+	_, moderatorToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+	_, adminToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleAdmin)
+
+	userURL := fmt.Sprintf("http://localhost:8080/v1/admin/users/%d", user.ID)
+
+	resp := adminRequest(t, http.MethodGet, userURL, userToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+	resp = adminRequest(t, http.MethodGet, userURL, moderatorToken, nil)
+	found := decodeAdminUser(t, resp)
+	assert.Equal(t, found.ID, user.ID)
+	assert.Equal(t, found.Role, entity.RoleUser)
+
+	resp = adminRequest(t, http.MethodPut, userURL+"/premium", moderatorToken, entity.SetPremiumRequest{IsPremium: true})
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+	resp = adminRequest(t, http.MethodPut, userURL+"/premium", adminToken, entity.SetPremiumRequest{IsPremium: true})
+	found = decodeAdminUser(t, resp)
+	assert.Equal(t, found.IsPremium, true)
+
+	resp = adminRequest(t, http.MethodPost, userURL+"/like-quota/reset", adminToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	resp = adminRequest(t, http.MethodGet, "http://localhost:8080/v1/admin/users/0", moderatorToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+}
+
+// Moderators can only act on users they outrank, and a demotion applies to
+// tokens issued before it
+func TestAdminRoleHierarchy(t *testing.T) {
+	user, _ := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleUser)
+	moderator, moderatorToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+	otherModerator, _ := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+	admin, _ := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleAdmin)
+
+	suspend := entity.SuspendUserRequest{Days: 1, Reason: "testing"}
+
+	for _, target := range []entity.SignUpResponse{otherModerator, admin} {
+		url := fmt.Sprintf("http://localhost:8080/v1/admin/users/%d/suspend", target.ID)
+		resp := adminRequest(t, http.MethodPost, url, moderatorToken, suspend)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+	}
+
+	url := fmt.Sprintf("http://localhost:8080/v1/admin/users/%d/suspend", user.ID)
+	resp := adminRequest(t, http.MethodPost, url, moderatorToken, suspend)
+	found := decodeAdminUser(t, resp)
+	assert.Equal(t, found.Status, entity.AccountSuspended.String())
+
+	// Demoted without signing in again, the old token still claims moderator
+	err := globalResources.ORM.Model(&entity.User{}).Where("id = ?", moderator.ID).Update("role", entity.RoleUser).Error
+	if err != nil {
+		t.Fatalf("Failed to set role: %s", err)
+	}
+	globalResources.Redis.Del(fmt.Sprintf(":user:%d:record:v2", moderator.ID))
+
+	resp = adminRequest(t, http.MethodGet, fmt.Sprintf("http://localhost:8080/v1/admin/users/%d", user.ID), moderatorToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+}
+
+func adminRequest(t *testing.T, method, url, token string, body interface{}) *http.Response {
+	var reqBody io.Reader
+
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reqBody = bytes.NewBuffer(encoded)
+	}
+
+	req, _ := http.NewRequest(method, url, reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	return resp
+}
+
+func decodeAdminUser(t *testing.T, resp *http.Response) entity.AdminUserResponse {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	response := http_util.HTTPResponse[entity.AdminUserResponse]{}
+	response, err = http_util.DecodeBody(bodyBytes, response)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+
+	return response.Data
+}
@@ -0,0 +1,102 @@
+package match__test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	matchRepository "github.com/ghaniswara/dating-app/internal/repository/match"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"gotest.tools/assert"
+)
+
+// Match two users, then user2 blocks user1. The match should be ended and
+// neither should be able to see or swipe the other
+func TestBlockEndsMatch(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user1, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token1, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	username2 := faker.Username()
+	password2 := faker.Password()
+	email2 := faker.Email()
+
+	user2, err := helper_test.SignUpUser(t, username2, password2, email2)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token2, err := helper_test.SignInUser(t, email2, username2, password2)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	createMatchRequest(t, token1, uint(user2.ID), entity.ActionLike)
+	resp := createMatchRequest(t, token2, uint(user1.ID), entity.ActionLike)
+	assert.Equal(t, resp.OutcomeEnum, entity.OutcomeMatch)
+
+	blockRequest(t, http.MethodPost, token2, user1.ID)
+
+	matchRepo := matchRepository.NewMatchRepo(
+		globalResources.ORM,
+		globalResources.Redis,
+	)
+
+	matchedProfiles1, err := matchRepo.GetMatchedProfilesIDs(context.TODO(), user1.ID)
+	if err != nil {
+		t.Fatalf("Failed to get matched profiles: %s", err)
+	}
+
+	assert.Equal(t, len(matchedProfiles1), 0)
+
+	// The blocked user swiping the blocker should look like a missing profile
+	resp = createMatchRequest(t, token1, uint(user2.ID), entity.ActionPass)
+	assert.Equal(t, resp.OutcomeEnum, entity.OutcomeNotFound)
+
+	profiles, err := getMatchProfiles(t, token1, nil)
+	if err != nil {
+		t.Fatalf("Failed to get profiles: %s", err)
+	}
+
+	for _, profile := range profiles {
+		assert.Assert(t, int(profile.ID) != user2.ID)
+	}
+
+	// After unblocking, swiping is allowed again
+	blockRequest(t, http.MethodDelete, token2, user1.ID)
+
+	resp = createMatchRequest(t, token1, uint(user2.ID), entity.ActionPass)
+	assert.Assert(t, resp.OutcomeEnum != entity.OutcomeNotFound)
+}
+
+func blockRequest(t *testing.T, method, token string, userID int) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:8080/v1/blocks/%d", userID), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
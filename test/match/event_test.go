@@ -0,0 +1,96 @@
+package match__test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"gotest.tools/assert"
+)
+
+// Match two users while neither is connected, then resume the stream
+// from the start, the missed match event should be delivered
+func TestMatchEventResume(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user1, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token1, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	username2 := faker.Username()
+	password2 := faker.Password()
+	email2 := faker.Email()
+
+	user2, err := helper_test.SignUpUser(t, username2, password2, email2)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token2, err := helper_test.SignInUser(t, email2, username2, password2)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	createMatchRequest(t, token1, uint(user2.ID), entity.ActionLike)
+	createMatchRequest(t, token2, uint(user1.ID), entity.ActionLike)
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/v1/events", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token1)
+	req.Header.Set("Last-Event-ID", "0-0")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	var eventID, eventType, eventData string
+	scanner := bufio.NewScanner(resp.Body)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			eventID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			eventData = strings.TrimPrefix(line, "data: ")
+		}
+
+		if line == "" && eventID != "" {
+			break
+		}
+	}
+
+	var data entity.MatchEventData
+	if err := json.Unmarshal([]byte(eventData), &data); err != nil {
+		t.Fatalf("Failed to decode event data: %s", err)
+	}
+
+	assert.Equal(t, eventType, string(entity.EventMatch))
+	assert.Equal(t, data.ProfileID, user2.ID)
+}
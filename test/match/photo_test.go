@@ -0,0 +1,293 @@
+package match__test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	photoRepository "github.com/ghaniswara/dating-app/internal/repository/photo"
+	photoUseCase "github.com/ghaniswara/dating-app/internal/usecase/photo"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/ghaniswara/dating-app/pkg/moderation"
+	"github.com/ghaniswara/dating-app/pkg/storage"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"gotest.tools/assert"
+)
+
+// Uploads are stored in every size, the first one is the primary until
+// another is picked and deleting closes the gap in the order
+func TestPhotoUpload(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	if _, err := helper_test.SignUpUser(t, username, password, email); err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1200, 800)))
+
+	resp, first := uploadPhoto(t, token, buf.Bytes())
+	assert.Equal(t, resp.StatusCode, http.StatusCreated)
+	assert.Assert(t, first.Data.IsPrimary)
+	assert.Equal(t, first.Data.Width, 1200)
+
+	resp, err = http.Get(first.Data.URLs["small"])
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "image/jpeg")
+
+	resp, _ = uploadPhoto(t, token, []byte("<html>not a photo</html>"))
+	assert.Equal(t, resp.StatusCode, http.StatusUnsupportedMediaType)
+
+	resp, second := uploadPhoto(t, token, buf.Bytes())
+	assert.Equal(t, resp.StatusCode, http.StatusCreated)
+	assert.Assert(t, !second.Data.IsPrimary)
+	assert.Equal(t, second.Data.Position, 1)
+
+	photoRequest(t, token, http.MethodPut, fmt.Sprintf("/v1/profile/photos/%d/primary", second.Data.ID), nil, http.StatusOK)
+
+	body, _ := json.Marshal(entity.ReorderPhotosRequest{PhotoIDs: []int{second.Data.ID}})
+	photoRequest(t, token, http.MethodPut, "/v1/profile/photos/order", body, http.StatusBadRequest)
+
+	body, _ = json.Marshal(entity.ReorderPhotosRequest{PhotoIDs: []int{second.Data.ID, first.Data.ID}})
+	photoRequest(t, token, http.MethodPut, "/v1/profile/photos/order", body, http.StatusOK)
+
+	photoRequest(t, token, http.MethodDelete, fmt.Sprintf("/v1/profile/photos/%d", first.Data.ID), nil, http.StatusOK)
+
+	resp, err = http.Get(first.Data.URLs["small"])
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+
+	photos := photoRequest(t, token, http.MethodGet, "/v1/profile/photos", nil, http.StatusOK)
+	assert.Equal(t, len(photos.Data.Photos), 1)
+	assert.Equal(t, photos.Data.Photos[0].ID, second.Data.ID)
+	assert.Equal(t, photos.Data.Photos[0].Position, 0)
+	assert.Assert(t, photos.Data.Photos[0].IsPrimary)
+}
+
+// Uploads stay pending until moderated. The rule moderator rejects a blank
+// photo and deletes its files, every other photo waits in the admin review
+// queue, and other users only see a photo once a reviewer approved it
+func TestPhotoModeration(t *testing.T) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	owner, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	// Only verified users are dealt in the deck
+	globalResources.ORM.Model(&entity.User{}).Where("id = ?", owner.ID).Update("email_verified_at", time.Now())
+
+	resp, passed := uploadPhoto(t, token, encodeNoise(t, 800, 600))
+	assert.Equal(t, resp.StatusCode, http.StatusCreated)
+	assert.Equal(t, passed.Data.Status, entity.PhotoPending.String())
+
+	var blank bytes.Buffer
+	png.Encode(&blank, image.NewRGBA(image.Rect(0, 0, 800, 600)))
+
+	_, rejected := uploadPhoto(t, token, blank.Bytes())
+	_, panorama := uploadPhoto(t, token, encodeNoise(t, 1600, 400))
+
+	_, moderatorToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+
+	// The owner's profile is the only one left in the moderator's deck
+	deckPhotos := func() []int {
+		var others []int
+		globalResources.ORM.Model(&entity.User{}).Where("id <> ?", owner.ID).Pluck("id", &others)
+
+		profiles, _ := getMatchProfiles(t, moderatorToken, others)
+
+		var ids []int
+		for _, profile := range profiles {
+			if int(profile.ID) != owner.ID {
+				continue
+			}
+			for _, photo := range profile.Photos {
+				ids = append(ids, int(photo.ID))
+			}
+		}
+		return ids
+	}
+
+	photoCase := photoUseCase.New(
+		photoRepository.NewPhotoRepo(globalResources.ORM),
+		storage.NewFilesystemStore(globalResources.Config.Get("STORAGE_DIR"), ""),
+		moderation.NewRuleModerator(),
+	)
+
+	// Moderate them now instead of waiting for the worker, the worker may
+	// have claimed them first so wait for it to save its decisions
+	var photos http_util.HTTPResponse[entity.PhotoListResponse]
+	var queued []int
+	for i := 0; i < 20; i++ {
+		if _, err := photoCase.ModeratePending(context.TODO(), 100); err != nil {
+			t.Fatalf("Failed to moderate photos: %s", err)
+		}
+
+		photos = photoRequest(t, token, http.MethodGet, "/v1/profile/photos", nil, http.StatusOK)
+
+		queued = nil
+		queue := reviewRequest(t, moderatorToken, http.MethodGet, "/v1/admin/photos?limit=100", nil, http.StatusOK)
+		for _, photo := range queue.Data.Photos {
+			queued = append(queued, photo.ID)
+		}
+
+		if photos.Data.Photos[1].Status != entity.PhotoPending.String() &&
+			slices.Contains(queued, passed.Data.ID) && slices.Contains(queued, panorama.Data.ID) {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	// Passing the rules only sends the photo to a reviewer
+	assert.Equal(t, photos.Data.Photos[0].ID, passed.Data.ID)
+	assert.Equal(t, photos.Data.Photos[0].Status, entity.PhotoPending.String())
+	assert.Equal(t, photos.Data.Photos[1].ID, rejected.Data.ID)
+	assert.Equal(t, photos.Data.Photos[1].Status, entity.PhotoRejected.String())
+	assert.Equal(t, photos.Data.Photos[1].ModerationNote, "photo is blank")
+	assert.Equal(t, len(photos.Data.Photos[1].URLs), 0)
+	assert.Equal(t, photos.Data.Photos[2].Status, entity.PhotoPending.String())
+
+	assert.Assert(t, slices.Contains(queued, passed.Data.ID))
+	assert.Assert(t, slices.Contains(queued, panorama.Data.ID))
+	assert.Assert(t, !slices.Contains(queued, rejected.Data.ID))
+
+	resp, err = http.Get(rejected.Data.URLs["small"])
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+
+	// Pending and rejected photos are hidden from other users
+	assert.Equal(t, len(deckPhotos()), 0)
+
+	body, _ := json.Marshal(entity.RejectPhotoRequest{})
+	reviewRequest(t, moderatorToken, http.MethodPost, fmt.Sprintf("/v1/admin/photos/%d/reject", panorama.Data.ID), body, http.StatusBadRequest)
+
+	reviewRequest(t, moderatorToken, http.MethodPost, fmt.Sprintf("/v1/admin/photos/%d/approve", passed.Data.ID), nil, http.StatusOK)
+	reviewRequest(t, moderatorToken, http.MethodPost, fmt.Sprintf("/v1/admin/photos/%d/approve", passed.Data.ID), nil, http.StatusConflict)
+
+	body, _ = json.Marshal(entity.RejectPhotoRequest{Reason: "not a photo of a person"})
+	reviewRequest(t, moderatorToken, http.MethodPost, fmt.Sprintf("/v1/admin/photos/%d/reject", panorama.Data.ID), body, http.StatusOK)
+	reviewRequest(t, moderatorToken, http.MethodPost, fmt.Sprintf("/v1/admin/photos/%d/reject", passed.Data.ID), body, http.StatusConflict)
+
+	resp, err = http.Get(panorama.Data.URLs["small"])
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+
+	assert.DeepEqual(t, deckPhotos(), []int{passed.Data.ID})
+}
+
+func uploadPhoto(t *testing.T, token string, data []byte) (*http.Response, http_util.HTTPResponse[entity.PhotoResponse]) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("photo", "photo.png")
+	part.Write(data)
+	form.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/v1/profile/photos", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var photo http_util.HTTPResponse[entity.PhotoResponse]
+	json.NewDecoder(resp.Body).Decode(&photo)
+	return resp, photo
+}
+
+func photoRequest(t *testing.T, token, method, path string, body []byte, status int) http_util.HTTPResponse[entity.PhotoListResponse] {
+	req, _ := http.NewRequest(method, "http://localhost:8080"+path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, status)
+
+	var photos http_util.HTTPResponse[entity.PhotoListResponse]
+	json.NewDecoder(resp.Body).Decode(&photos)
+	return photos
+}
+
+func reviewRequest(t *testing.T, token, method, path string, body []byte, status int) http_util.HTTPResponse[entity.PhotoReviewListResponse] {
+	req, _ := http.NewRequest(method, "http://localhost:8080"+path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, status)
+
+	var photos http_util.HTTPResponse[entity.PhotoReviewListResponse]
+	json.NewDecoder(resp.Body).Decode(&photos)
+	return photos
+}
+
+// Random pixels, never mistaken for a blank photo
+func encodeNoise(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rand.Read(img.Pix)
+
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode photo: %s", err)
+	}
+	return buf.Bytes()
+}
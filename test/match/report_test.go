@@ -0,0 +1,150 @@
+package match__test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"gotest.tools/assert"
+)
+
+// A reported profile goes to the moderation queue and can be reviewed only once
+func TestReportModeration(t *testing.T) {
+	profiles, err := helper_test.PopulateUsers(globalResources.ORM, 1)
+	if err != nil {
+		t.Fatalf("Failed to populate users: %s", err)
+	}
+
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	if _, err := helper_test.SignUpUser(t, username, password, email); err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	body, _ := json.Marshal(entity.CreateReportRequest{
+		ReportedUserID: int(profiles[0].ID),
+		Reason:         entity.ReportReasonSpam,
+		Details:        "sends links to every match",
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/v1/reports", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	report := decodeReport(t, req)
+	assert.Equal(t, report.Status, entity.ReportStatusPending.String())
+
+	// The reported profile is hidden from the reporter's deck
+	matchProfiles, err := getMatchProfiles(t, token, nil)
+	if err != nil {
+		t.Fatalf("Failed to get profiles: %s", err)
+	}
+
+	for _, profile := range matchProfiles {
+		assert.Assert(t, profile.ID != profiles[0].ID)
+	}
+
+	moderator, moderatorToken := helper_test.SignInWithRole(t, globalResources.ORM, entity.RoleModerator)
+
+	dismissURL := fmt.Sprintf("http://localhost:8080/v1/admin/reports/%d/dismiss", report.ID)
+
+	req, _ = http.NewRequest(http.MethodPost, dismissURL, nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+	report = decodeReport(t, req)
+	assert.Equal(t, report.Status, entity.ReportStatusDismissed.String())
+	assert.Assert(t, report.ReviewerID != nil && int(*report.ReviewerID) == moderator.ID)
+
+	req, _ = http.NewRequest(http.MethodPost, dismissURL, nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusConflict)
+
+	// Moderators reviewing the same report at once, only one of them resolves it
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/v1/reports", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	report = decodeReport(t, req)
+	dismissURL = fmt.Sprintf("http://localhost:8080/v1/admin/reports/%d/dismiss", report.ID)
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, _ := http.NewRequest(http.MethodPost, dismissURL, nil)
+			req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("Failed to send request: %s", err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+
+	wg.Wait()
+	close(statuses)
+
+	resolved := 0
+	for status := range statuses {
+		if status == http.StatusOK {
+			resolved++
+			continue
+		}
+		assert.Equal(t, status, http.StatusConflict)
+	}
+	assert.Equal(t, resolved, 1)
+}
+
+func decodeReport(t *testing.T, req *http.Request) entity.ReportResponse {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	response := http_util.HTTPResponse[entity.ReportResponse]{}
+	response, err = http_util.DecodeBody(bodyBytes, response)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+
+	return response.Data
+}
@@ -0,0 +1,69 @@
+package moderation_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/pkg/moderation"
+	"github.com/stretchr/testify/assert"
+)
+
+// Blank and tiny photos are rejected, everything else goes to a reviewer.
+// The rules never approve a photo on their own
+func TestRuleModerator(t *testing.T) {
+	moderator := moderation.NewRuleModerator()
+
+	cases := []struct {
+		name    string
+		photo   []byte
+		verdict moderation.Verdict
+	}{
+		{"photo", encodeJPEG(t, noise(640, 480)), moderation.VerdictReview},
+		{"blank", encodeJPEG(t, plain(640, 480, color.RGBA{40, 90, 200, 255})), moderation.VerdictReject},
+		{"too small", encodeJPEG(t, noise(150, 150)), moderation.VerdictReject},
+		{"panorama", encodeJPEG(t, noise(1080, 270)), moderation.VerdictReview},
+		{"not an image", []byte("not an image"), moderation.VerdictReject},
+	}
+
+	for _, c := range cases {
+		result, err := moderator.Moderate(context.Background(), c.photo)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.verdict, result.Verdict, c.name)
+		assert.NotEmpty(t, result.Reason, c.name)
+	}
+}
+
+func TestManualModerator(t *testing.T) {
+	result, err := moderation.NewManualModerator().Moderate(context.Background(), encodeJPEG(t, noise(640, 480)))
+	assert.NoError(t, err)
+	assert.Equal(t, moderation.VerdictReview, result.Verdict)
+}
+
+func noise(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rand.Read(img.Pix)
+	return img
+}
+
+func plain(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode photo: %s", err)
+	}
+	return buf.Bytes()
+}
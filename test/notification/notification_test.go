@@ -0,0 +1,214 @@
+package notification_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/pkg/http_util"
+	"github.com/ghaniswara/dating-app/pkg/push"
+	helper_test "github.com/ghaniswara/dating-app/test/helper"
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var globalResources *helper_test.TestServerResources
+
+func TestMain(m *testing.M) {
+	// Set up the test server
+	resources, err := helper_test.SetupTestServer(context.TODO())
+	var code int
+
+	if err != nil {
+		log.Printf("Failed to set up test server: %s", err)
+		code = 1
+	} else {
+		// Run tests
+		globalResources = resources
+		code = m.Run()
+	}
+
+	resources.CleanupTestServer()
+	os.Exit(code)
+}
+
+// Registering the same token twice should keep a single device
+func TestRegisterDevice(t *testing.T) {
+	token := signUpAndSignIn(t)
+	deviceToken := faker.UUIDDigit()
+
+	for i := 0; i < 2; i++ {
+		resp := doRequest(t, http.MethodPost, "/v1/devices", token, entity.RegisterDeviceRequest{
+			Token:    deviceToken,
+			Platform: entity.PlatformFCM,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	var count int64
+	globalResources.ORM.Model(&entity.Device{}).Where("token = ?", deviceToken).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	resp := doRequest(t, http.MethodPost, "/v1/devices", token, entity.RegisterDeviceRequest{
+		Token:    deviceToken,
+		Platform: "sms",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	token := signUpAndSignIn(t)
+
+	resp := doRequest(t, http.MethodGet, "/v1/notifications/preferences", token, nil)
+	preferences := decodePreferences(t, resp)
+	assert.True(t, preferences.Match)
+	assert.True(t, preferences.SuperLike)
+
+	disabled := false
+	resp = doRequest(t, http.MethodPut, "/v1/notifications/preferences", token, entity.UpdateNotificationPreferencesRequest{
+		SuperLike: &disabled,
+	})
+	preferences = decodePreferences(t, resp)
+	assert.True(t, preferences.Match)
+	assert.False(t, preferences.SuperLike)
+
+	resp = doRequest(t, http.MethodGet, "/v1/notifications/preferences", token, nil)
+	preferences = decodePreferences(t, resp)
+	assert.False(t, preferences.SuperLike)
+}
+
+// A match goes through the outbox, the match consumer and the push queue
+// before the dispatcher hands it to the provider of the earlier swiper's device
+func TestMatchPushNotification(t *testing.T) {
+	first, firstToken := signUpWithID(t)
+	second, secondToken := signUpWithID(t)
+	deviceToken := faker.UUIDDigit()
+
+	resp := doRequest(t, http.MethodPost, "/v1/devices", firstToken, entity.RegisterDeviceRequest{
+		Token:    deviceToken,
+		Platform: entity.PlatformFCM,
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doRequest(t, http.MethodPost, fmt.Sprintf("/v1/match/profile/%d/like", second.ID), firstToken, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doRequest(t, http.MethodPost, fmt.Sprintf("/v1/match/profile/%d/like", first.ID), secondToken, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	provider, ok := globalResources.Server.PushProvider(entity.PlatformFCM).(*push.FakeProvider)
+	if !ok {
+		t.Fatalf("Expected the fake push provider")
+	}
+
+	var sent *push.Message
+	for deadline := time.Now().Add(20 * time.Second); sent == nil && time.Now().Before(deadline); {
+		for _, msg := range provider.Sent() {
+			if msg.Token == deviceToken {
+				sent = &msg
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if sent == nil {
+		t.Fatalf("Push notification was not sent")
+	}
+
+	assert.Equal(t, string(entity.NotificationMatch), sent.Data["type"])
+	assert.Equal(t, fmt.Sprint(second.ID), sent.Data["profile_id"])
+}
+
+func signUpWithID(t *testing.T) (entity.SignUpResponse, string) {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	user, err := helper_test.SignUpUser(t, username, password, email)
+	if err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	return user, token
+}
+
+func signUpAndSignIn(t *testing.T) string {
+	username := faker.Username()
+	password := faker.Password()
+	email := faker.Email()
+
+	if _, err := helper_test.SignUpUser(t, username, password, email); err != nil {
+		t.Fatalf("Failed to sign up user: %s", err)
+	}
+
+	token, err := helper_test.SignInUser(t, email, username, password)
+	if err != nil {
+		t.Fatalf("Failed to sign in user: %s", err)
+	}
+
+	return token
+}
+
+func doRequest(t *testing.T, method, path, token string, body interface{}) *http.Response {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to marshal request body: %s", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequest(method, "http://localhost:8080"+path, reqBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	return resp
+}
+
+func decodePreferences(t *testing.T, resp *http.Response) entity.NotificationPreferencesResponse {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	response := http_util.HTTPResponse[entity.NotificationPreferencesResponse]{}
+	response, err = http_util.DecodeBody(bodyBytes, response)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+
+	return response.Data
+}
@@ -0,0 +1,103 @@
+//go:build oidcfake
+
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+)
+
+// The authorization code flow against the fake issuer, the ID token is only
+// accepted with the nonce of the request
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer, err := oidc.NewFakeIssuer("client")
+	if err != nil {
+		t.Fatalf("Failed to start issuer: %v", err)
+	}
+	defer issuer.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      issuer.URL(),
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+	}, nil)
+
+	verifier, _ := oidc.RandomString()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", oidc.CodeChallenge(verifier), "user@example.com")
+	assert.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "state", callback.Query().Get("state"))
+
+	// A wrong verifier fails PKCE and burns the code
+	_, err = provider.Exchange(context.Background(), callback.Query().Get("code"), "wrong")
+	assert.Error(t, err)
+
+	resp, err = client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ = url.Parse(resp.Header.Get("Location"))
+
+	rawIDToken, err := provider.Exchange(context.Background(), callback.Query().Get("code"), verifier)
+	assert.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, "other")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	idToken, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", idToken.Email)
+	assert.True(t, idToken.EmailVerified)
+	assert.NotEmpty(t, idToken.Subject)
+}
+
+// A token from another client is refused
+func TestVerifyAudience(t *testing.T) {
+	issuer, err := oidc.NewFakeIssuer("client")
+	if err != nil {
+		t.Fatalf("Failed to start issuer: %v", err)
+	}
+	defer issuer.Close()
+
+	provider := oidc.NewProvider(oidc.Config{Issuer: issuer.URL(), ClientID: "client", RedirectURL: "http://localhost/callback"}, nil)
+	other := oidc.NewProvider(oidc.Config{Issuer: issuer.URL(), ClientID: "other", RedirectURL: "http://localhost/callback"}, nil)
+
+	verifier, _ := oidc.RandomString()
+	authURL, _ := provider.AuthCodeURL(context.Background(), "state", "nonce", oidc.CodeChallenge(verifier), "")
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	rawIDToken, err := provider.Exchange(context.Background(), callback.Query().Get("code"), verifier)
+	assert.NoError(t, err)
+
+	_, err = other.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
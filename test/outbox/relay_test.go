@@ -0,0 +1,199 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/internal/entity"
+	"github.com/ghaniswara/dating-app/internal/events"
+	outboxWorker "github.com/ghaniswara/dating-app/internal/worker/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+// A poison event is retried with a backoff without holding up later events,
+// and dead lettered once it runs out of attempts
+func TestRelayDeadLettersPoisonEvent(t *testing.T) {
+	repo := newFakeOutbox(
+		entity.OutboxEvent{ID: 1, Topic: "match.created", Payload: `{}`},
+		entity.OutboxEvent{ID: 2, Topic: "poison", Payload: `{}`},
+		entity.OutboxEvent{ID: 3, Topic: "match.created", Payload: `{}`},
+	)
+	publisher := &fakePublisher{fail: "poison"}
+	relay := outboxWorker.NewRelay(repo, publisher)
+
+	published, err := relay.Flush(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"1"}, publisher.keys())
+
+	// The failed event waits for its retry, the next one goes out meanwhile
+	published, err = relay.Flush(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"1", "3"}, publisher.keys())
+	assert.NotNil(t, repo.event(3).PublishedAt)
+
+	published, err = relay.Flush(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.Equal(t, 1, repo.event(2).Attempts)
+
+	for i := 0; i < 8; i++ {
+		repo.skipRetryWait(2)
+		_, err := relay.Flush(context.TODO())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 9, repo.event(2).Attempts)
+	assert.Nil(t, repo.event(2).DeadAt)
+	assert.Equal(t, "broker down", repo.event(2).LastError)
+
+	// Retries back off instead of hammering the bus
+	assert.True(t, repo.retryAt[2].After(time.Now().Add(time.Minute)))
+
+	// The last attempt dead letters it
+	repo.skipRetryWait(2)
+	_, err = relay.Flush(context.TODO())
+	assert.NoError(t, err)
+	assert.NotNil(t, repo.event(2).DeadAt)
+	assert.Nil(t, repo.event(2).PublishedAt)
+	assert.Equal(t, []string{"1", "3"}, publisher.keys())
+}
+
+// Keeps events in memory, skipRetryWait makes an event available without waiting
+type fakeOutbox struct {
+	mu      sync.Mutex
+	events  map[uint]*entity.OutboxEvent
+	claimed map[uint]bool
+	retryAt map[uint]time.Time
+}
+
+func newFakeOutbox(events ...entity.OutboxEvent) *fakeOutbox {
+	repo := &fakeOutbox{
+		events:  map[uint]*entity.OutboxEvent{},
+		claimed: map[uint]bool{},
+		retryAt: map[uint]time.Time{},
+	}
+
+	for i := range events {
+		repo.events[events[i].ID] = &events[i]
+	}
+
+	return repo
+}
+
+func (f *fakeOutbox) event(id uint) entity.OutboxEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return *f.events[id]
+}
+
+func (f *fakeOutbox) ClaimPending(_ context.Context, limit int, _ time.Duration) ([]entity.OutboxEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pending []entity.OutboxEvent
+	for _, event := range f.events {
+		if event.PublishedAt == nil && event.DeadAt == nil && !f.claimed[event.ID] && !f.retryAt[event.ID].After(time.Now()) {
+			pending = append(pending, *event)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	for _, event := range pending {
+		f.claimed[event.ID] = true
+	}
+
+	return pending, nil
+}
+
+func (f *fakeOutbox) skipRetryWait(id uint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.retryAt[id] = time.Now()
+}
+
+func (f *fakeOutbox) MarkPublished(_ context.Context, id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	f.events[id].PublishedAt = &now
+	delete(f.claimed, id)
+
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(_ context.Context, event entity.OutboxEvent, cause error, retryAt time.Time, maxAttempts int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored := f.events[event.ID]
+	stored.Attempts++
+	stored.LastError = cause.Error()
+	f.retryAt[event.ID] = retryAt
+	delete(f.claimed, event.ID)
+
+	if stored.Attempts >= maxAttempts {
+		now := time.Now()
+		stored.DeadAt = &now
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (f *fakeOutbox) ReleaseClaims(_ context.Context, ids []uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range ids {
+		delete(f.claimed, id)
+	}
+
+	return nil
+}
+
+func (f *fakeOutbox) DeletePublishedBefore(_ context.Context, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	fail      string
+	published []events.Message
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msg events.Message) error {
+	if msg.Topic == p.fail {
+		return errors.New("broker down")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.published = append(p.published, msg)
+
+	return nil
+}
+
+func (p *fakePublisher) keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var keys []string
+	for _, msg := range p.published {
+		keys = append(keys, msg.Key)
+	}
+
+	return keys
+}
@@ -0,0 +1,124 @@
+package photo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/pkg/photo"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every variant is a JPEG scaled down to its longest side, smaller uploads
+// aren't scaled up
+func TestProcess(t *testing.T) {
+	processed, err := photo.Process(encodePNG(t, 3000, 1500))
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, processed.Width)
+	assert.Equal(t, 1024, processed.Height)
+
+	sizes := map[string]image.Point{
+		"original": {2048, 1024},
+		"large":    {1080, 540},
+		"medium":   {640, 320},
+		"small":    {240, 120},
+	}
+
+	for _, variant := range photo.Variants {
+		decoded, err := jpeg.Decode(bytes.NewReader(processed.Variants[variant.Name]))
+		assert.NoError(t, err)
+		assert.Equal(t, sizes[variant.Name], decoded.Bounds().Size(), variant.Name)
+	}
+
+	processed, err = photo.Process(encodePNG(t, 200, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, processed.Width)
+	assert.Equal(t, 100, processed.Height)
+}
+
+// The EXIF is dropped but its orientation is applied first, a landscape
+// JPEG rotated 90 degrees comes out portrait
+func TestOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, newImage(200, 100), nil))
+
+	withExif := append([]byte{}, buf.Bytes()[:2]...)
+	withExif = append(withExif, exifSegment(6)...)
+	withExif = append(withExif, buf.Bytes()[2:]...)
+
+	processed, err := photo.Process(withExif)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, processed.Width)
+	assert.Equal(t, 200, processed.Height)
+	assert.False(t, bytes.Contains(processed.Variants["original"], []byte("Exif")))
+}
+
+// The content type comes from the bytes, not the file name or header
+func TestUnsupportedType(t *testing.T) {
+	_, err := photo.Process([]byte("GIF89a not really a photo"))
+	assert.ErrorIs(t, err, photo.ErrUnsupportedType)
+
+	_, err = photo.Process([]byte("<html><body>hello</body></html>"))
+	assert.ErrorIs(t, err, photo.ErrUnsupportedType)
+
+	_, err = photo.Process(append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, 32)...))
+	assert.ErrorIs(t, err, photo.ErrInvalidImage)
+}
+
+// Only the header is read before refusing, so a tiny file claiming huge
+// dimensions costs nothing
+func TestTooManyPixels(t *testing.T) {
+	_, err := photo.Process(pngHeader(5000, 4001))
+	assert.ErrorIs(t, err, photo.ErrTooManyPixels)
+
+	_, err = photo.Process(pngHeader(5000, 4000))
+	assert.NotErrorIs(t, err, photo.ErrTooManyPixels)
+}
+
+func newImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, newImage(w, h)))
+	return buf.Bytes()
+}
+
+// APP1 segment with a big endian TIFF holding only the orientation tag
+func exifSegment(orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // header, IFD0 at 8
+		0x00, 0x01, // 1 entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00, // orientation, SHORT
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+
+	return append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+}
+
+// A PNG signature and IHDR chunk without any image data
+func pngHeader(w, h uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 2, 0, 0, 0)
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(ihdr)-4))
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
@@ -0,0 +1,90 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ghaniswara/dating-app/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesystemStore(t *testing.T) {
+	store := storage.NewFilesystemStore(t.TempDir(), "http://localhost:8080/media/")
+	testStore(t, store)
+
+	assert.Equal(t, "http://localhost:8080/media/photos/1/a.jpg", store.URL("photos/1/a.jpg"))
+
+	// Keys can't escape the directory
+	assert.Error(t, store.Put(context.TODO(), "../outside.jpg", []byte("x"), "image/jpeg"))
+}
+
+// Against a fake bucket checking requests are path-style and signed
+func TestS3Store(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/photos-bucket/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := storage.NewS3Store(storage.S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "photos-bucket",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+	testStore(t, store)
+
+	assert.Equal(t, server.URL+"/photos-bucket/photos/1/a.jpg", store.URL("photos/1/a.jpg"))
+}
+
+func testStore(t *testing.T, store storage.BlobStore) {
+	ctx := context.TODO()
+
+	assert.NoError(t, store.Put(ctx, "photos/1/a.jpg", []byte("jpeg"), "image/jpeg"))
+
+	r, err := store.Open(ctx, "photos/1/a.jpg")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "jpeg", string(data))
+
+	assert.NoError(t, store.Delete(ctx, "photos/1/a.jpg"))
+	assert.NoError(t, store.Delete(ctx, "photos/1/a.jpg"))
+
+	_, err = store.Open(ctx, "photos/1/a.jpg")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
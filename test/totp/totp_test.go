@@ -0,0 +1,55 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghaniswara/dating-app/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238 appendix B, the 8 digit codes cut to 6
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := totp.Code(secret, totp.Step(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+// A code is accepted one step early or late and returns its own step
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	previous, err := totp.Code(secret, totp.Step(now)-1)
+	assert.NoError(t, err)
+
+	step, ok := totp.Validate(secret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now)-1, step)
+
+	_, ok = totp.Validate(secret, previous, now.Add(2*totp.Period), 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Dating App", "user@example.com", "ABCDEF")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Dating%20App:user@example.com?"))
+	assert.Contains(t, uri, "secret=ABCDEF")
+	assert.Contains(t, uri, "issuer=Dating+App")
+}